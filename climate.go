@@ -24,7 +24,9 @@ import (
 //	func([ctx context.Context], [opts *T], [args []string]) [(err error)]
 //
 // All of ctx, opts, args and error are optional. If opts is present, T must be
-// a struct (whose fields are used as flags).
+// a struct (whose fields are used as flags). If *T also has a method conforming
+// to func([ctx context.Context]) error named Validate, it's called after the
+// flags are parsed (but before f) and any error is treated as a usage error.
 func Func(f any) *funcPlan {
 	t := reflect.TypeOf(f)
 	assert.Truef(t.Kind() == reflect.Func, "not a func: %v", t)
@@ -38,7 +40,11 @@ var _ internal.Plan = (*funcPlan)(nil)
 // with its methods* (and "child" structs) as subcommands.
 //
 // * Only methods with pointer receiver are considered (and they must otherwise
-// conform to the same signatures described in Func).
+// conform to the same signatures described in Func). Validate (if it conforms
+// to func([ctx context.Context]) error) is an exception, which is instead
+// called (like in Func) before any of the subcommands run, as are Complete<X>
+// methods (where X is a field or another method), which are instead used to
+// complete the values of the flag / args of the subcommand X.
 func Struct[T any](subcommands ...*structPlan) *structPlan {
 	t := reflect.TypeFor[T]()
 	assert.Truef(t.Kind() == reflect.Struct, "not a struct: %v", t)
@@ -79,20 +85,93 @@ func WithMetadata(b []byte) func(*internal.RunOptions) {
 	}
 }
 
-// Run executes the given plan and returns the exit code.
-func Run(ctx context.Context, p internal.Plan, mods ...func(*internal.RunOptions)) int {
+// WithCompletionInstaller returns a modifier that adds "install" and
+// "uninstall" subcommands to the (Cobra provided) completion command, which
+// install / uninstall the completion script for the given shell in the
+// standard per-user location for that shell.
+func WithCompletionInstaller() func(*internal.RunOptions) {
+	return func(opts *internal.RunOptions) {
+		opts.CompletionInstaller = true
+	}
+}
+
+// WithTheme returns a modifier that styles help and error output with the
+// given theme (DefaultTheme, say), but only when the output is a terminal (or
+// FORCE_COLOR is set) and NO_COLOR is not set.
+func WithTheme(theme Theme) func(*internal.RunOptions) {
+	return func(opts *internal.RunOptions) {
+		opts.Theme = &theme
+	}
+}
+
+// WithUsageTemplate returns a modifier that sets the usage template (for all the
+// commands) to the given Go text/template, which is executed with the (Cobra)
+// command as data. Along with Cobra's template funcs, these are available --
+//  1. flagUsages $ <flags>: aligned (and grouped) tables of the given flags.
+//  2. heading $ <text>: the given text styled as a heading (see WithTheme).
+//  3. wrapText $ <text>: the given text wrapped to fit in the terminal.
+//  4. runnable <command>: whether the command is runnable (unlike structs).
+//  5. describe <command>: the CommandSpec (see Describe) of the command, with
+//     climate specific info like params, flag groups and validation rules.
+//
+// See DefaultUsageTemplate for an example.
+func WithUsageTemplate(t string) func(*internal.RunOptions) {
+	return func(opts *internal.RunOptions) {
+		opts.UsageTemplate = t
+	}
+}
+
+// WithHelpTemplate returns a modifier that sets the help template (for all the
+// commands) to the given Go text/template, similar to WithUsageTemplate.
+//
+// See DefaultHelpTemplate for an example.
+func WithHelpTemplate(t string) func(*internal.RunOptions) {
+	return func(opts *internal.RunOptions) {
+		opts.HelpTemplate = t
+	}
+}
+
+// WithMissingMetadataHook returns a modifier that calls hook with the funcs /
+// types (as pkgPath.name) the plan references but are missing from the metadata
+// (see WithMetadata), which usually means that the metadata is stale (i.e., go
+// generate needs to be run again). See also cligen --check.
+func WithMissingMetadataHook(hook func(missing []string)) func(*internal.RunOptions) {
+	return func(opts *internal.RunOptions) {
+		opts.MissingMetadata = hook
+	}
+}
+
+func newRunOptions(mods []func(*internal.RunOptions)) (*internal.RunOptions, *internal.Metadata) {
 	var opts internal.RunOptions
 	for _, mod := range mods {
 		mod(&opts)
 	}
 	var md *internal.Metadata
 	if opts.Metadata != nil {
-		md = internal.DecodeAsMetadata(*opts.Metadata)
+		md = assert.Ok(internal.DecodeAsMetadata(*opts.Metadata))
 	}
+	return &opts, md
+}
+
+// Run executes the given plan and returns the exit code.
+func Run(ctx context.Context, p internal.Plan, mods ...func(*internal.RunOptions)) int {
+	opts, md := newRunOptions(mods)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	// Cobra already prints the error to stderr, so just return exit code here.
-	return exitCode(p.Execute(ctx, md))
+	return exitCode(p.Execute(ctx, md, opts))
+}
+
+// build builds and sets up the command tree for the given plan (without
+// executing it).
+func build(p internal.Plan, mods []func(*internal.RunOptions)) *command {
+	pl, ok := p.(plan)
+	assert.Truef(ok, "not a plan returned by Func or Struct: %T", p)
+	opts, md := newRunOptions(mods)
+	cmd := pl.build(md)
+	reportMissing(md, opts)
+	cmd.setup(opts)
+	return cmd
 }
 
 // RunAndExit executes the given plan and exits with the exit code.
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/avamsi/climate/internal"
+)
+
+// cacheVersion is to be bumped on any change to how the metadata is parsed, so
+// that the metadata cached by older versions is not reused.
+const cacheVersion = 1
+
+// cache caches package metadata (encoded) keyed by the hashes of its files, in
+// a directory (or not at all if dir is empty).
+type cache struct {
+	dir string
+}
+
+func newCache() *cache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return &cache{} // caching is best effort
+	}
+	return &cache{filepath.Join(dir, "cligen")}
+}
+
+// key returns a key for the given package, which changes whenever any of its
+// files (or the set of files, given the build tags) changes.
+func (c *cache) key(pkg *packages.Package) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "cligen v%v, metadata v%v\n%v %v\n",
+		cacheVersion, internal.MetadataVersion, pkg.Name, pkg.PkgPath)
+	for _, path := range pkg.GoFiles {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%v\n", path)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *cache) get(key string) (*internal.RawMetadata, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	b, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return nil, false
+	}
+	rmd, err := internal.DecodeAsRawMetadata(b)
+	if err != nil { // corrupted (by a concurrent write, say), just reparse
+		return nil, false
+	}
+	return rmd, true
+}
+
+func (c *cache) put(key string, rmd *internal.RawMetadata) {
+	if c.dir == "" || os.MkdirAll(c.dir, 0o755) != nil {
+		return
+	}
+	// Write to a temporary file and rename, so that concurrent runs (go
+	// generate ./... in parallel, say) never see partially written files.
+	f, err := os.CreateTemp(c.dir, key+".*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(rmd.Encode())
+	if err = errors.Join(err, f.Close()); err == nil {
+		err = os.Rename(f.Name(), filepath.Join(c.dir, key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// parsePkgFast is like parsePkg, but parses the package files directly (which
+// go list already filtered by build tags) without type checking them, and
+// reuses the cached metadata if none of the files changed.
+func parsePkgFast(pkg *packages.Package, rootMd *internal.RawMetadata, c *cache) error {
+	key, err := c.key(pkg)
+	if err != nil {
+		return err
+	}
+	pkgMd := pkgMetadata(pkg, rootMd)
+	if cached, ok := c.get(key); ok {
+		*pkgMd = *cached
+		return nil
+	}
+	fset := token.NewFileSet()
+	for _, path := range pkg.GoFiles {
+		file, err := parser.ParseFile(fset, path, nil,
+			parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		parseFile(file, pkgMd)
+	}
+	c.put(key, pkgMd)
+	return nil
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"go/ast"
 	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
-	"reflect"
+	"slices"
+	"strings"
 
 	_ "embed"
 
@@ -63,7 +67,19 @@ func parseType(g *ast.GenDecl, pkgMd *internal.RawMetadata) {
 	}
 }
 
-func parsePkg(pkg *packages.Package, rootMd *internal.RawMetadata) {
+func parseFile(file *ast.File, pkgMd *internal.RawMetadata) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			parseFunc(decl, pkgMd)
+		case *ast.GenDecl:
+			parseType(decl, pkgMd)
+		}
+	}
+}
+
+// pkgMetadata returns the metadata for the given package from rootMd.
+func pkgMetadata(pkg *packages.Package, rootMd *internal.RawMetadata) *internal.RawMetadata {
 	pkgMd := rootMd.Child(pkg.PkgPath)
 	if pkg.Name == "main" {
 		// main packages are special, in that they're standalone in production
@@ -72,18 +88,14 @@ func parsePkg(pkg *packages.Package, rootMd *internal.RawMetadata) {
 		// TODO: stop duplicating the package metadata here.
 		rootMd.Children["main"] = pkgMd
 	}
+	return pkgMd
+}
+
+func parsePkg(pkg *packages.Package, rootMd *internal.RawMetadata) {
+	pkgMd := pkgMetadata(pkg, rootMd)
 	for node := range pkg.TypesInfo.Scopes {
-		file, ok := node.(*ast.File)
-		if !ok {
-			continue
-		}
-		for _, decl := range file.Decls {
-			switch decl := decl.(type) {
-			case *ast.FuncDecl:
-				parseFunc(decl, pkgMd)
-			case *ast.GenDecl:
-				parseType(decl, pkgMd)
-			}
+		if file, ok := node.(*ast.File); ok {
+			parseFile(file, pkgMd)
 		}
 	}
 }
@@ -97,52 +109,239 @@ func pkgDir(pkg *packages.Package) string {
 
 type options struct {
 	Debug bool // whether to print metadata
+	Check bool // only check that the output file is up to date (without writing it)
+	// parse files without type checking (and cache the metadata by file hashes)
+	Fast bool
+	Pkg  string // `path` of the main package to scope the metadata to (along with its imports)
+}
+
+// reachable returns the packages (from the main modules, i.e., not from the
+// dependencies) reachable from the given packages via imports.
+func reachable(pkgs []*packages.Package) []*packages.Package {
+	var deps []*packages.Package
+	packages.Visit(pkgs, func(pkg *packages.Package) bool {
+		if pkg.Module == nil || !pkg.Module.Main {
+			return false
+		}
+		deps = append(deps, pkg)
+		return true
+	}, nil)
+	return deps
+}
+
+// load loads the packages to parse the metadata of, which are all the packages
+// in the current directory (and its subdirectories) or, if scoped (see --pkg),
+// the packages reachable from the given main package.
+func load(opts *options) ([]*packages.Package, error) {
+	mode := packages.NeedName | packages.NeedFiles
+	if !opts.Fast {
+		mode |= packages.NeedTypes | packages.NeedTypesInfo
+	}
+	patterns := []string{"./..."}
+	if opts.Pkg != "" {
+		// Resolve the reachable packages with go list first (which is cheap),
+		// so that only those are type checked below (if at all).
+		cfg := &packages.Config{
+			Mode: (packages.NeedName | packages.NeedFiles | packages.NeedImports |
+				packages.NeedDeps | packages.NeedModule),
+		}
+		pkgs, err := packages.Load(cfg, opts.Pkg)
+		if err != nil {
+			return nil, err
+		}
+		if len(pkgs) != 1 {
+			return nil, fmt.Errorf("--pkg %v: not exactly one package (%v)", opts.Pkg, len(pkgs))
+		}
+		if pkg := pkgs[0]; len(pkg.Errors) > 0 {
+			errs := make([]error, len(pkg.Errors))
+			for i, err := range pkg.Errors {
+				errs[i] = err
+			}
+			return nil, fmt.Errorf("--pkg %v: %w", opts.Pkg, errors.Join(errs...))
+		} else if pkg.Name != "main" {
+			return nil, fmt.Errorf("--pkg %v: not a main package: %v", opts.Pkg, pkg.PkgPath)
+		}
+		deps := reachable(pkgs)
+		if len(deps) == 0 {
+			return nil, fmt.Errorf("--pkg %v: not in the main module", opts.Pkg)
+		}
+		if opts.Fast {
+			return deps, nil
+		}
+		patterns = patterns[:0]
+		for _, pkg := range deps {
+			patterns = append(patterns, pkg.PkgPath)
+		}
+	}
+	return packages.Load(&packages.Config{Mode: mode}, patterns...)
 }
 
 // cligen recursively parses the metadata of all Go packages in the current
 // directory and its subdirectories, and writes it to the given output file.
-func cligen(opts *options, out string) {
+//
+// With --pkg, cligen instead parses only the packages (from the main module)
+// reachable from the given main package, which is useful for repos with many
+// binaries (cligen --pkg ./cmd/foo cmd/foo/md.cli, say).
+//
+// With --fast, cligen only parses the files (without type checking them, which
+// is slow for large modules), and skips even that for packages whose files are
+// unchanged since the last run (see the cligen directory in os.UserCacheDir).
+//
+// With --check, cligen instead exits non-zero (printing what changed) if the
+// output file is out of date, which is useful in CI to catch forgotten go
+// generate runs.
+//
+// cligen also has these subcommands (see cligen <command> --help) --
+//
+//	cligen docs <dir>          generate Markdown reference docs
+//	cligen dump <path>         print the metadata as a human readable tree
+//	cligen diff <old> <new>    print the differences between the metadata
+func cligen(opts *options, out string) error {
+	pkgs, err := load(opts)
+	if err != nil {
+		return err
+	}
 	var (
-		rootMd internal.RawMetadata
-		mode   = (packages.NeedName | packages.NeedFiles |
-			packages.NeedTypes | packages.NeedTypesInfo)
-		cfg      = &packages.Config{Mode: mode}
-		pkgs     = assert.Ok(packages.Load(cfg, "./..."))
+		rootMd   internal.RawMetadata
 		rootDir  = assert.Ok(filepath.Abs(assert.Ok(os.Getwd())))
+		cache    = newCache()
 		mainPkgs []string
 	)
 	for _, pkg := range pkgs {
 		if pkg.Name == "main" {
-			if pkgDir(pkg) != rootDir {
-				// Skip non-root main packages.
+			if opts.Pkg == "" && pkgDir(pkg) != rootDir {
+				// Skip non-root main packages (unless explicitly asked for).
 				continue
 			}
 			mainPkgs = append(mainPkgs, pkg.PkgPath)
-			assert.Truef(len(mainPkgs) <= 1,
-				"more than one main packages: %v", mainPkgs)
 		}
-		parsePkg(pkg, &rootMd)
+		if !opts.Fast {
+			parsePkg(pkg, &rootMd)
+		} else if err := parsePkgFast(pkg, &rootMd, cache); err != nil {
+			return err
+		}
+	}
+	if len(mainPkgs) > 1 {
+		// They'd all be duplicated as "main" (see pkgMetadata).
+		return fmt.Errorf("more than one main packages %v, use --pkg for each", mainPkgs)
 	}
 	if opts.Debug {
 		litter.Dump(rootMd)
 	}
-	// gob encoding is not deterministic with maps, which means the encoded
-	// metadata (and hence the output file) will keep changing on each
-	// invocation even if nothing about the metadata actually changed, which is
-	// not ideal (causes VCS noise, for example). To avoid this, we compare the
-	// new metadata with the old one and write only if they are different.
+	// The encoding is deterministic, so only write if the metadata actually
+	// changed (to avoid touching the file unnecessarily, for one).
+	newEncoded := rootMd.Encode()
 	oldEncoded, err := os.ReadFile(out)
+	if opts.Check {
+		if err != nil {
+			return err
+		}
+		return check(out, oldEncoded, newEncoded, &rootMd)
+	}
 	if !errors.Is(err, fs.ErrNotExist) { // if exists
 		assert.Nil(err)
-		oldDecoded := internal.DecodeAsRawMetadata(oldEncoded)
-		if reflect.DeepEqual(rootMd, *oldDecoded) {
-			return
+		if bytes.Equal(oldEncoded, newEncoded) {
+			return nil
 		}
 	}
 	// #nosec G306 -- G306 expects 0o600 or less but 0o644 is fine here as the
 	// metadata is not really sensitive (and is expected to be committed).
-	assert.Nil(os.WriteFile(out, rootMd.Encode(), 0o644))
+	assert.Nil(os.WriteFile(out, newEncoded, 0o644))
 	fmt.Println("cligen: (re)generated", out)
+	return nil
+}
+
+// check returns an error describing the changes if the old encoded metadata
+// (from the output file) is not up to date with the new metadata.
+func check(out string, oldEncoded, newEncoded []byte, newMd *internal.RawMetadata) error {
+	if bytes.Equal(oldEncoded, newEncoded) {
+		return nil
+	}
+	oldMd, err := internal.DecodeAsRawMetadata(oldEncoded)
+	if err != nil {
+		return fmt.Errorf("%v is out of date: %w", out, err)
+	}
+	diff := internal.Diff(oldMd, newMd)
+	if diff == "" {
+		// Same metadata but encoded differently (by an older cligen, say).
+		return fmt.Errorf("%v is out of date (format only), run go generate", out)
+	}
+	return fmt.Errorf("%v is out of date, run go generate:\n%v", out, strings.TrimSuffix(diff, "\n"))
+}
+
+// tools are cligen's subcommands (cligen itself generates metadata, see above).
+//
+//cli:usage cligen <command>
+type tools struct{}
+
+// Generate Markdown reference docs for the main package in the current
+// directory to the given directory.
+//
+// This runs the main package (with go run and the hidden --generate-markdown
+// flag), which writes one Markdown file per command (instead of actually
+// running the command) -- see also climate.GenerateMarkdown.
+func (*tools) Docs(ctx context.Context, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "go", "run", ".", "--generate-markdown="+dir)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func readMetadata(path string) (*internal.RawMetadata, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rmd, err := internal.DecodeAsRawMetadata(b)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return rmd, nil
+}
+
+// Print the metadata in the given file as a human readable tree.
+//
+// Packages, types, funcs and fields are printed one per line (indentation
+// implies nesting), followed by their docs, directives and examples (rendered
+// like in Go source).
+//
+// Examples:
+//
+//	cligen dump md.cli
+func (*tools) Dump(path string) error {
+	rmd, err := readMetadata(path)
+	if err != nil {
+		return err
+	}
+	fmt.Print(internal.Dump(rmd))
+	return nil
+}
+
+// Print the differences between the metadata in the given files.
+//
+// Entries (funcs, types, fields etc.) added, removed and changed are printed
+// one per line (prefixed with +, - and ~ respectively), with the changes to
+// the docs, directives etc. indented below the changed ones.
+//
+// Examples:
+//
+//	git show HEAD:md.cli > /tmp/old.cli && cligen diff /tmp/old.cli md.cli
+//
+//cli:usage diff <old> <new>
+func (*tools) Diff(paths [2]string) error {
+	oldMd, err := readMetadata(paths[0])
+	if err != nil {
+		return err
+	}
+	newMd, err := readMetadata(paths[1])
+	if err != nil {
+		return err
+	}
+	fmt.Print(internal.Diff(oldMd, newMd))
+	return nil
 }
 
 //go:generate go tool cligen md.cli
@@ -150,5 +349,10 @@ func cligen(opts *options, out string) {
 var md []byte
 
 func main() {
+	// cligen is mostly run as `cligen <out>` (by go:generate directives), so
+	// only dispatch to the subcommands (tools) when explicitly asked to.
+	if len(os.Args) > 1 && slices.Contains([]string{"docs", "dump", "diff"}, os.Args[1]) {
+		climate.RunAndExit(climate.Struct[tools](), climate.WithMetadata(md))
+	}
 	climate.RunAndExit(climate.Func(cligen), climate.WithMetadata(md))
 }
@@ -21,11 +21,23 @@ import (
 //	5. Field docs / comments are used* as flag usage strings (as is).
 //	6. "required" subfield tags (under the "cli" tags) are used to mark the
 //	   flags as required (i.e., the command is errored out without these flags).
+//	7. "min", "max", "pattern", "nonempty", "exists" and "isdir" subfield tags
+//	   (under the "cli" tags) are used to validate the flag values before the
+//	   command is run (values with commas aren't supported, so "pattern=a,b"
+//	   panics, as do unknown subfield tags).
+//	8. "alias" subfield tags (under the "cli" tags) are used to declare
+//	   alternative (pipe separated) names for the flags ("alias=repo|r", say).
+//	9. "name" subfield tags (under the "cli" tags) are used as flag names as is
+//	   (instead of the kebab-cased field names; "name=ipv6" for IPv6, say).
+//	10. Nested (and embedded) struct fields are declared as flags as well, with
+//	    their own group in help (embedded ones aren't prefixed with the field
+//	    name). "group" subfield tags (under the "cli" tags) are used to group
+//	    flags (or override the heading of a nested group) explicitly.
 
 type greetOptions struct {
 	Greeting string `cli:"short" default:"Hello"`   // greeting to use
 	Name     string `cli:"short=n" default:"World"` // name to greet
-	Times    int    `cli:"short,required"`          // number of times to greet
+	Times    int    `cli:"short,required,min=1"`    // number of times to greet
 }
 
 // Func is automatically converted to a command --
@@ -21,24 +21,56 @@ import (
 //	   2. Method docs are truncated and are used* as short help strings.
 //	   3. Method directives are used* to declare aliases or explicitly set the
 //	      short help strings (//cli:aliases, for example).
+//	   4. //cli:hidden and //cli:deprecated [message] directives are used* to
+//	      hide and deprecate commands (similar to the "hidden" and
+//	      "deprecated" subfield tags for flags), with a default message if
+//	      none is given.
+//	   5. //cli:file [patterns] and //cli:dir directives are used* to complete
+//	      args with files (*.go|*.mod, say) or directories (similar to the
+//	      "file" and "dir" subfield tags for flags, which only complete and
+//	      don't validate, see the "exists" and "isdir" rules for that).
+//	   6. //cli:example <example> directives (which can be repeated) and godoc
+//	      style "Examples:" blocks are used* as examples in help.
+//	   7. //cli:group <title> directives are used* to group commands in help
+//	      (in the order of their first appearance, with the ungrouped commands
+//	      listed last, under "Other Commands").
 //	4. "Sub-structs" are automatically converted to subcommands, recursively.
 
 // Jujutsu (an experimental VCS).
 type jj struct {
-	Repository        string `cli:"short=R"` // `path` to the repo to operate on
-	IgnoreWorkingCopy bool   // don't snapshot / update the working copy
+	Repository          string `cli:"short=R,alias=repo,dir"` // `path` to the repo to operate on
+	IgnoreWorkingCopy   bool   // don't snapshot / update the working copy
+	NoCommitWorkingCopy bool   `cli:"deprecated=use --ignore-working-copy instead"`
 }
 
 // Create a new repo in the given directory.
+//
+//cli:dir
+//cli:group Core Commands
 func (j *jj) Init(ctx context.Context, dir *string) {
 	fmt.Println("init", ctx, j, dir)
 }
 
+// Low-level commands not intended for users.
+//
+//cli:hidden
+func (j *jj) Debug() {
+	fmt.Println("debug", j)
+}
+
 type squashOptions struct {
 	Revision    string `cli:"short" default:"@"`
 	Interactive bool   `cli:"short"` // interactively choose which parts to squash
 }
 
+// Complete<Field> methods on options structs (and structs) are used to complete
+// the values of the corresponding flags (--revision, in this case). Similarly,
+// Complete methods on options structs (and Complete<Method> methods on structs)
+// are used to complete the args of the corresponding commands.
+func (opts *squashOptions) CompleteRevision(ctx context.Context, toComplete string) []string {
+	return []string{"@", "@-", "root()"}
+}
+
 // Move changes from a revision into its parent.
 //
 // After moving the changes into the parent, the child revision will have the
@@ -46,7 +78,14 @@ type squashOptions struct {
 // compared to its parent, it will be abandoned. Without `--interactive`, the
 // child change will always be empty.
 //
+// Examples:
+//
+//	jj squash
+//	jj squash -r @- src/main.go
+//
 //cli:aliases am, amend
+//cli:group Core Commands
+//cli:example jj squash --interactive
 func (j *jj) Squash(opts *squashOptions, paths [5]string) {
 	fmt.Println("squash", j, opts, paths)
 }
@@ -25,16 +25,19 @@ func TestMain(t *testing.T) {
 Usage:
   jj [command]
 
-Available Commands:
+Core Commands:
+  init        Create a new repo in the given directory
+  squash      Move changes from a revision into its parent
+
+Other Commands:
   completion  Generate the autocompletion script for the specified shell
   git         Commands for working with the underlying Git repo
   help        Help about any command
-  init        Create a new repo in the given directory
-  squash      Move changes from a revision into its parent
   util        Infrequently used commands such as for generating shell completions
 
 Flags:
-  -R, --repository          path  path to the repo to operate on
+  -R, --repository          path  path to the repo to operate on (aliases:
+                                  --repo)
       --ignore-working-copy       don't snapshot / update the working copy
   -h, --help                      help for jj
 
@@ -51,16 +54,19 @@ Use "jj [command] --help" for more information about a command.
 Usage:
   jj [command]
 
-Available Commands:
+Core Commands:
+  init        Create a new repo in the given directory
+  squash      Move changes from a revision into its parent
+
+Other Commands:
   completion  Generate the autocompletion script for the specified shell
   git         Commands for working with the underlying Git repo
   help        Help about any command
-  init        Create a new repo in the given directory
-  squash      Move changes from a revision into its parent
   util        Infrequently used commands such as for generating shell completions
 
 Flags:
-  -R, --repository          path  path to the repo to operate on
+  -R, --repository          path  path to the repo to operate on (aliases:
+                                  --repo)
       --ignore-working-copy       don't snapshot / update the working copy
   -h, --help                      help for jj
 
@@ -86,9 +92,45 @@ Flags:
 
 Global Flags:
       --ignore-working-copy       don't snapshot / update the working copy
-  -R, --repository          path  path to the repo to operate on
+  -R, --repository          path  path to the repo to operate on (aliases:
+                                  --repo)
 
 Use "jj git [command] --help" for more information about a command.
+`,
+			},
+		},
+		{
+			name: "jj-squash--help",
+			args: []string{"squash", "--help"},
+			want: clitest.Result{
+				Stdout: `Move changes from a revision into its parent.
+
+After moving the changes into the parent, the child revision will have the
+same content state as before. If that means that the change is now empty
+compared to its parent, it will be abandoned. Without ` + "`--interactive`" + `, the
+child change will always be empty.
+
+Usage:
+  jj squash [opts] <paths...>
+
+Aliases:
+  squash, am, amend
+
+Examples:
+  jj squash
+  jj squash -r @- src/main.go
+  jj squash --interactive
+
+Flags:
+  -r, --revision    string (default @)  
+  -i, --interactive                     interactively choose which parts to
+                                        squash
+  -h, --help                            help for squash
+
+Global Flags:
+      --ignore-working-copy       don't snapshot / update the working copy
+  -R, --repository          path  path to the repo to operate on (aliases:
+                                  --repo)
 `,
 			},
 		},
@@ -112,6 +154,96 @@ Run 'jj git --help' for usage.
 			want: clitest.Result{
 				Stderr: `Error: unknown command "x" for "jj git"
 Run 'jj git --help' for usage.
+`,
+				Code: 1,
+			},
+		},
+		{
+			name: "jj--no-commit-working-copy-debug",
+			args: []string{"--no-commit-working-copy", "debug"},
+			want: clitest.Result{
+				Stdout: "debug &{ false true}\n",
+				Stderr: "Flag --no-commit-working-copy has been deprecated, use --ignore-working-copy instead\n",
+			},
+		},
+		{
+			name: "jj-git-export--repo",
+			args: []string{"git", "export", "--repo=."},
+			want: clitest.Result{
+				Stdout: "export &{. false false}\n",
+			},
+		},
+		{
+			name: "jj-__complete-squash--revision",
+			args: []string{"__complete", "squash", "--revision", ""},
+			want: clitest.Result{
+				Stdout: "@\n@-\nroot()\n:4\n",
+				Stderr: "Completion ended with directive: ShellCompDirectiveNoFileComp\n",
+			},
+		},
+		{
+			name: "jj-__complete-init",
+			args: []string{"__complete", "init", ""},
+			want: clitest.Result{
+				Stdout: ":16\n",
+				Stderr: "Completion ended with directive: ShellCompDirectiveFilterDirs\n",
+			},
+		},
+		{
+			name: "jj-__complete--repository",
+			args: []string{"__complete", "--repository", ""},
+			want: clitest.Result{
+				Stdout: ":16\n",
+				Stderr: "Completion ended with directive: ShellCompDirectiveFilterDirs\n",
+			},
+		},
+		{
+			name: "jj-util-completion--help",
+			args: []string{"util", "completion", "--help"},
+			want: clitest.Result{
+				Stdout: `Print a command-line-completion script.
+
+Usage:
+  jj util completion [opts]
+
+Flags:
+      --bash  print a completion script for the given shell
+      --zsh   print a completion script for the given shell
+      --fish  print a completion script for the given shell
+  -h, --help  help for completion
+
+Constraints:
+  exactly one of --bash, --zsh, --fish
+
+Global Flags:
+      --ignore-working-copy       don't snapshot / update the working copy
+  -R, --repository          path  path to the repo to operate on (aliases:
+                                  --repo)
+`,
+			},
+		},
+		{
+			name: "jj-util-completion--bash--zsh",
+			args: []string{"util", "completion", "--bash", "--zsh"},
+			want: clitest.Result{
+				Stderr: `Error: if any flags in the group [bash zsh fish] are set none of the others can be; [bash zsh] were all set
+Usage:
+  jj util completion [opts]
+
+Flags:
+      --bash  print a completion script for the given shell
+      --zsh   print a completion script for the given shell
+      --fish  print a completion script for the given shell
+  -h, --help  help for completion
+
+Constraints:
+  exactly one of --bash, --zsh, --fish
+
+Global Flags:
+      --ignore-working-copy       don't snapshot / update the working copy
+  -R, --repository          path  path to the repo to operate on (aliases:
+                                  --repo)
+
 `,
 				Code: 1,
 			},
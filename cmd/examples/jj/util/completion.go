@@ -2,8 +2,11 @@ package util
 
 import "fmt"
 
+// "oneof" subfield tags (under the "cli" tags) declare that exactly one of the
+// flags in the named group must be set ("exclusive", "anyof" and "together"
+// similarly declare at most one, at least one and all or none respectively).
 type completionOptions struct {
-	Bash, Zsh, Fish bool // print a completion script for the given shell
+	Bash, Zsh, Fish bool `cli:"oneof=shell"` // print a completion script for the given shell
 }
 
 // Print a command-line-completion script.
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
 	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"text/tabwriter"
@@ -26,10 +28,14 @@ type command struct {
 
 func newCommand(name string, md *internal.Metadata, params []internal.ParamType) *command {
 	delegate := cobra.Command{
-		Use:     md.Usage(name, params),
-		Aliases: md.Aliases(),
-		Short:   md.Short(),
-		Long:    md.Long(),
+		Use:        md.Usage(name, params),
+		Aliases:    md.Aliases(),
+		Short:      md.Short(),
+		Long:       md.Long(),
+		Example:    md.Example(),
+		Hidden:     md.Hidden(),
+		Deprecated: md.Deprecated(),
+		GroupID:    md.Group(),
 	}
 	delegate.Flags().SortFlags = false
 	delegate.PersistentFlags().SortFlags = false
@@ -40,6 +46,11 @@ func newCommand(name string, md *internal.Metadata, params []internal.ParamType)
 }
 
 func (cmd *command) addCommand(sub *command) {
+	// Groups are added in the order of their first appearance (and we use the
+	// titles as IDs, as it's the titles that are specified in directives).
+	if id := sub.delegate.GroupID; id != "" && !cmd.delegate.ContainsGroup(id) {
+		cmd.delegate.AddGroup(&cobra.Group{ID: id, Title: id + ":"})
+	}
 	cmd.delegate.AddCommand(&sub.delegate)
 }
 
@@ -75,29 +86,95 @@ func version() string {
 	return module.PseudoVersion("", "", t, rev)
 }
 
-func flagUsages(fset *pflag.FlagSet) string {
+func flagTable(flags []*pflag.Flag, width int, theme Theme) string {
+	if len(flags) == 0 {
+		return ""
+	}
 	var (
-		b strings.Builder
-		t = tabwriter.NewWriter(&b, 0, 0, 0, ' ', 0)
+		b      strings.Builder
+		t      = tabwriter.NewWriter(&b, 0, 0, 0, ' ', 0)
+		usages []string
 	)
-	fset.VisitAll(func(f *pflag.Flag) {
-		var short string
+	for _, f := range flags {
+		// Pad the shorthand column even if there are no shorthands so that all
+		// the tables line up (shorthands are always exactly one character).
+		// Styles (if any) are applied to all the cells of a column, even if
+		// empty, so that they all have the same (invisible) width to align.
+		short := style(theme.Flag, "") + "    "
 		if f.Shorthand != "" {
-			short = fmt.Sprintf("-%v, ", f.Shorthand)
+			short = style(theme.Flag, "-"+f.Shorthand) + ", "
 		}
 		var (
 			qtype, usage = pflag.UnquoteUsage(f)
 			value        string
 		)
+		// Note: the spaces are kept out of the styles (see below).
 		if qtype != "" {
-			qtype += " "
+			qtype = style(theme.Type, qtype) + " "
+		} else {
+			qtype = style(theme.Type, "")
 		}
 		if _, ok := f.Annotations[nonZeroDefault]; ok {
-			value = fmt.Sprintf("(default %v) ", f.DefValue)
+			value = style(theme.Default, fmt.Sprintf("(default %v)", f.DefValue)) + " "
+		} else {
+			value = style(theme.Default, "")
 		}
-		fmt.Fprintf(t, "  %v\t--%v\t %v\t%v \t%v\n", short, f.Name, qtype, value, usage)
-	})
+		if aliases, ok := f.Annotations[flagAliases]; ok {
+			usage = strings.TrimSpace(
+				fmt.Sprintf("%v (aliases: --%v)", usage, strings.Join(aliases, ", --")))
+		}
+		// Usages are appended after aligning the other columns (see below).
+		fmt.Fprintf(t, "  %v\t%v\t %v\t%v \t\n", short, style(theme.Flag, "--"+f.Name), qtype, value)
+		usages = append(usages, usage)
+	}
 	t.Flush()
+	// All the lines are of the same width now, so the usages can be wrapped
+	// under their own column (when they don't fit in the given width).
+	var (
+		lines = strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+		table strings.Builder
+	)
+	for i, line := range lines {
+		// Empty cells are only styled to align the styled cells, drop them now
+		// (so that trailing whitespace is trimmed as usual, for one).
+		line = emptyStyleRegexp.ReplaceAllString(line, "")
+		usage := wrapColumn(usages[i], visibleWidth(line), width)
+		fmt.Fprintf(&table, "%v%v\n", line, usage)
+	}
+	return table.String()
+}
+
+// flagUsages renders the (non-hidden) flags in fset as aligned tables, with the
+// usages wrapped to fit in width (if non-zero) and styled as per theme.
+func flagUsages(fset *pflag.FlagSet, width int, theme Theme) string {
+	var (
+		ungrouped []*pflag.Flag
+		titles    []string
+		groups    = map[string][]*pflag.Flag{}
+	)
+	fset.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		group, ok := f.Annotations[flagGroup]
+		if !ok {
+			ungrouped = append(ungrouped, f)
+			return
+		}
+		title := group[0]
+		if _, ok := groups[title]; !ok {
+			titles = append(titles, title)
+		}
+		groups[title] = append(groups[title], f)
+	})
+	// Each group is rendered as its own table (aligned independently), under
+	// its own title, after the ungrouped flags.
+	var b strings.Builder
+	b.WriteString(flagTable(ungrouped, width, theme))
+	for _, title := range titles {
+		fmt.Fprintf(&b, "\n%v\n%v", style(theme.Heading, title+":"), flagTable(groups[title], width, theme))
+	}
+	b.WriteString(constraintUsages(fset, theme))
 	return b.String()
 }
 
@@ -114,13 +191,79 @@ func versionCommand(name, v string) *cobra.Command {
 	}
 }
 
-func (cmd *command) run(ctx context.Context) error {
-	normalize := func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
-		return pflag.NormalizedName(internal.NormalizeToKebabCase(name))
+// addExplicitFlagNames adds the names of the explicitly named flags (see
+// option.flagName) in fset to names.
+func addExplicitFlagNames(names map[string]bool, fset *pflag.FlagSet) {
+	fset.VisitAll(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[flagExplicitName]; ok {
+			names[f.Name] = true
+		}
+	})
+}
+
+// setNormalization sets the flag normalization for cmd and its subcommands
+// (recursively), leaving the explicitly named flags of each command (including
+// the persistent ones it inherits, given as inherited) untouched.
+//
+// Note: explicit names need to be collected upfront (instead of checking the
+// given flag set for them), as pflag normalizes names even as it adds flags to
+// a flag set (and Cobra keeps adding flags to new flag sets).
+func setNormalization(cmd *cobra.Command, inherited map[string]bool) {
+	persistent := maps.Clone(inherited)
+	addExplicitFlagNames(persistent, cmd.PersistentFlags())
+	explicit := maps.Clone(persistent)
+	addExplicitFlagNames(explicit, cmd.Flags())
+	// This also sets the normalization for the subcommands, which is then
+	// overridden below (with their own explicit names).
+	cmd.SetGlobalNormalizationFunc(func(fset *pflag.FlagSet, name string) pflag.NormalizedName {
+		return pflag.NormalizedName(normalizeFlagName(fset, name, explicit))
+	})
+	for _, sub := range cmd.Commands() {
+		setNormalization(sub, persistent)
+	}
+}
+
+var errIntercepted = errors.New("intercepted")
+
+// intercept makes root run do instead (of actually running root) when active,
+// which is checked after the flags are parsed but before the positional args,
+// required flags etc. are validated (as root isn't actually run after all).
+// This is meant for hidden flags like --generate-completion and --help-json.
+func intercept(root *cobra.Command, active func() bool, do func(*cobra.Command) error) {
+	if validateArgs := root.Args; validateArgs != nil {
+		root.Args = func(cmd *cobra.Command, args []string) error {
+			if active() {
+				return nil
+			}
+			return validateArgs(cmd, args)
+		}
+	}
+	preRunE := root.PreRunE
+	root.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if !active() {
+			if preRunE != nil {
+				return preRunE(cmd, args)
+			}
+			return nil
+		}
+		if err := do(cmd); err != nil {
+			return err
+		}
+		// Stop Cobra from running the command (see command.run).
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return errIntercepted
 	}
+}
+
+// setup finalizes the command tree rooted at cmd, before it's executed (or
+// otherwise used, to generate man pages, for example).
+func (cmd *command) setup(opts *internal.RunOptions) {
 	// While we prefer kebab-case for flags, we do support other well-formed,
 	// cases through normalization (but only kebab-case shows up in --help).
-	cmd.delegate.SetGlobalNormalizationFunc(normalize)
+	// Flag aliases are resolved as part of the normalization as well, while
+	// explicitly named flags are left untouched.
+	setNormalization(&cmd.delegate, map[string]bool{})
 	if v := version(); v != "" {
 		// Add the version subcommand only when the root command already has
 		// subcommands (similar to how Cobra does it for help / completion).
@@ -129,19 +272,40 @@ func (cmd *command) run(ctx context.Context) error {
 		}
 		cmd.delegate.Version = v
 	}
-	// Align the flag usages as a table (pflag's FlagUsages already does this to
-	// some extent but doesn't align types and default values).
-	cobra.AddTemplateFunc("flagUsages", flagUsages)
-	t := cmd.delegate.UsageTemplate()
-	t = strings.ReplaceAll(t, ".FlagUsages", " | flagUsages")
-	cmd.delegate.SetUsageTemplate(t)
-	return cmd.delegate.ExecuteContext(ctx)
+	if cmd.delegate.HasSubCommands() {
+		cmd.delegate.AddCommand(manCommand())
+	}
+	if !cmd.delegate.HasSubCommands() {
+		// Cobra only adds the completion command when there are subcommands,
+		// so provide a hidden flag instead (similar to version above).
+		addCompletionFlag(&cmd.delegate)
+	}
+	addHelpJSONFlag(&cmd.delegate)
+	addMarkdownFlag(&cmd.delegate)
+	if opts.CompletionInstaller && cmd.delegate.HasSubCommands() {
+		// Cobra only adds the completion command (when there are subcommands)
+		// during execution, so add it now to be able to extend it.
+		cmd.delegate.InitDefaultCompletionCmd()
+		addCompletionInstaller(&cmd.delegate)
+	}
+	setupTemplates(&cmd.delegate, opts)
+}
+
+func (cmd *command) run(ctx context.Context, opts *internal.RunOptions) error {
+	cmd.setup(opts)
+	if err := cmd.delegate.ExecuteContext(ctx); !errors.Is(err, errIntercepted) {
+		return err
+	}
+	return nil
 }
 
 type funcCommandBuilder struct {
 	name string
 	reflection
 	md *internal.Metadata
+	// validators of the ancestor struct commands (if any), which are run (in
+	// order) before the function, along with its own options' validator.
+	validators []validator
 }
 
 type runSignature struct {
@@ -154,6 +318,14 @@ type runSignature struct {
 
 func (fcb *funcCommandBuilder) run(sig *runSignature) func(*cobra.Command, []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		// Flags (including any inherited ones) are all parsed by now, so this
+		// is the earliest we can validate them.
+		if err := validateFlags(cmd.Flags()); err != nil {
+			return err
+		}
+		if err := validate(cmd.Context(), fcb.validators); err != nil {
+			return err
+		}
 		var in []reflect.Value
 		if sig.inCtx {
 			in = append(in, reflect.ValueOf(cmd.Context()))
@@ -224,15 +396,21 @@ func (fcb *funcCommandBuilder) build() *command {
 			var (
 				r    = reflection{ptr: &reflection{ot: t}}
 				opts = &options{
-					r,
-					nil, // no parent
-					cmd.delegate.Flags(),
-					fcb.md.LookupType(t.Elem()),
+					reflection: r,
+					parent:     nil, // no parent
+					cmd:        &cmd.delegate,
+					fset:       cmd.delegate.Flags(),
+					md:         fcb.md.LookupType(t.Elem()),
 				}
 			)
 			opts.declare()
+			markConstraints(&cmd.delegate, opts.fset)
 			i++
 			inOpts = r.ptr.v()
+			if v := validatorOf(*inOpts); v != nil {
+				// Make sure to not modify the (shared) ancestor validators.
+				fcb.validators = append(slices.Clip(fcb.validators), v)
+			}
 		}
 	}
 	if i < n {
@@ -260,6 +438,27 @@ func (fcb *funcCommandBuilder) build() *command {
 	} else {
 		cmd.delegate.Args = cobra.ExactArgs(0)
 	}
+	if exts, ok := fcb.md.Files(); ok || fcb.md.Dir() {
+		cmd.delegate.ValidArgsFunction = fileCompletionFunc(fcb.md.Dir(), fileExtensions(exts))
+	}
+	if inOpts != nil {
+		// Complete methods take precedence over file / dir directives.
+		if m := companion(*inOpts, "", true); m.IsValid() {
+			cmd.delegate.ValidArgsFunction = completionFunc(m, true)
+		}
+	}
+	if inArgs != internal.NoParam {
+		// Cobra doesn't keep track of the positional params, so record them
+		// for Describe (args is always the last param, see above).
+		name := "args"
+		if params := fcb.md.Params(); i-1 < len(params) {
+			name = params[i-1]
+		}
+		cmd.delegate.Annotations = map[string]string{
+			paramName: internal.NormalizeToKebabCase(name),
+			paramType: strconv.Itoa(int(inArgs)),
+		}
+	}
 	outErr := fcb.t().NumOut() == 1 && typeIsError(fcb.t().Out(0))
 	if i != n || fcb.t().IsVariadic() || (fcb.t().NumOut() != 0 && !outErr) {
 		ergo.Panicf("not func([context.Context], [*struct], [[]string]) [error]: %v", fcb.t())
@@ -272,6 +471,9 @@ type structCommandBuilder struct {
 	reflection
 	parent *reflection
 	md     *internal.Metadata
+	// validators of the ancestor struct commands and (after build) this struct
+	// command itself, in that order.
+	validators []validator
 }
 
 func validateNoArgs(cmd *cobra.Command, args []string) error {
@@ -283,9 +485,10 @@ func validateNoArgs(cmd *cobra.Command, args []string) error {
 	var b strings.Builder
 	fmt.Fprintf(&b, "%v", err)
 	if suggestions := cmd.SuggestionsFor(args[0]); len(suggestions) > 0 {
-		b.WriteString("\n\nDid you mean this?\n")
+		theme := helpOptionsOf(cmd).Err
+		fmt.Fprintf(&b, "\n\n%v\n", style(theme.Heading, "Did you mean this?"))
 		for _, s := range suggestions {
-			fmt.Fprintf(&b, "\t%v\n", s)
+			fmt.Fprintf(&b, "\t%v\n", style(theme.Suggestion, s))
 		}
 	}
 	fmt.Fprintf(&b, "\nRun '%v --help' for usage.", cmd.CommandPath())
@@ -296,43 +499,53 @@ func (scb *structCommandBuilder) build() *command {
 	var (
 		cmd  = newCommand(scb.t().Name(), scb.md, nil)
 		opts = &options{
-			scb.reflection,
-			scb.parent,
-			cmd.delegate.PersistentFlags(),
-			scb.md,
+			reflection: scb.reflection,
+			parent:     scb.parent,
+			cmd:        &cmd.delegate,
+			fset:       cmd.delegate.PersistentFlags(),
+			md:         scb.md,
 		}
 	)
 	opts.declare()
+	markConstraints(&cmd.delegate, opts.fset)
+	hook := validatorOf(*scb.ptr.v())
+	if hook != nil {
+		// Make sure to not modify the (shared) ancestor validators.
+		scb.validators = append(slices.Clip(scb.validators), hook)
+	}
 	for i := 0; i < scb.ptr.v().NumMethod(); i++ {
+		m := scb.ptr.t().Method(i)
+		if m.Name == "Validate" && hook != nil {
+			// Validate is a hook (see validatorOf), not a subcommand.
+			continue
+		}
+		if isCompanion(*scb.ptr.v(), m.Name) {
+			// Complete<field / method> methods are completion functions for
+			// flags / subcommands (see below), not subcommands themselves.
+			continue
+		}
 		var (
-			m   = scb.ptr.t().Method(i)
 			v   = scb.ptr.v().Method(i)
 			fcb = &funcCommandBuilder{
 				m.Name,
 				reflection{ov: &v},
 				scb.md.Child(m.Name),
+				scb.validators,
 			}
 		)
+		sub := fcb.build()
+		if c := companion(*scb.ptr.v(), m.Name, true); c.IsValid() {
+			sub.delegate.ValidArgsFunction = completionFunc(c, true)
+		}
 		// TODO: maybe provide an option to default to a subcommand.
-		cmd.addCommand(fcb.build())
+		cmd.addCommand(sub)
 	}
 	// This should ideally be as simple as setting cobra.NoArgs, but for
 	// whatever reason, Cobra doesn't really honor that for subcommands
 	// (see spf13/cobra#706, spf13/cobra#981) -- so, we do it ourselves.
 	cmd.delegate.RunE = validateNoArgs
-	// We only make this command "runnable" to validate NoArgs, so hack the
-	// usage template and pretend it's not really runnable.
-	// Note: Cobra subcommands will inherit any custom attributes set on the
-	// parent command, so we need to be careful here to only apply the changes
-	// to the parent command and not any subcommands.
-	defaultHelpFunc := cmd.delegate.HelpFunc()
-	cmd.delegate.SetHelpFunc(func(c *cobra.Command, _ []string) {
-		if c == &cmd.delegate {
-			t := cmd.delegate.UsageTemplate()
-			t = strings.ReplaceAll(t, "{{if .Runnable}}", "{{if false}}")
-			c.SetUsageTemplate(t)
-		}
-		defaultHelpFunc(c, nil)
-	})
+	// We only make this command "runnable" to validate NoArgs, so pretend it's
+	// not really runnable in the usage (see the runnable template func).
+	cmd.delegate.Annotations = map[string]string{structCommand: ""}
 	return cmd
 }
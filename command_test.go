@@ -0,0 +1,917 @@
+package climate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/avamsi/climate/internal"
+)
+
+type testPlan interface {
+	build(md *internal.Metadata) *command
+}
+
+func execute(p testPlan, args ...string) (string, error) {
+	// Fixed width, so that the tests don't depend on the terminal (see clitest).
+	return executeWithOptions(p, &internal.RunOptions{Width: 80}, args...)
+}
+
+func executeWithOptions(p testPlan, opts *internal.RunOptions, args ...string) (string, error) {
+	var (
+		cmd = p.build(nil) // no metadata
+		b   strings.Builder
+	)
+	cmd.delegate.SetArgs(args)
+	cmd.delegate.SetOut(&b)
+	cmd.delegate.SetErr(&b)
+	// TODO(golang/go#36532): replace with t.Context().
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := cmd.run(ctx, opts)
+	return b.String(), err
+}
+
+type serverOptions struct {
+	Host string `default:"localhost"`
+	Port int    `default:"8080"`
+}
+
+type serveOptions struct {
+	Verbose bool `cli:"short"`
+	Server  serverOptions
+	Backup  serverOptions `cli:"prefix=bak"`
+}
+
+var served *serveOptions
+
+func serve(opts *serveOptions) {
+	served = opts
+}
+
+func TestNestedOptions(t *testing.T) {
+	{
+		want := `Usage:
+  serve [flags]
+
+Flags:
+  -v, --verbose  
+  -h, --help     help for serve
+
+Server Flags:
+      --server-host string (default localhost)  
+      --server-port int    (default 8080)       
+
+Backup Flags:
+      --bak-host string (default localhost)  
+      --bak-port int    (default 8080)
+`
+		got, err := execute(Func(serve), "--help")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("diff(-want +got):\n%v", diff)
+		}
+	}
+	{
+		want := &serveOptions{
+			Server: serverOptions{Host: "example.com", Port: 8080},
+			Backup: serverOptions{Host: "localhost", Port: 9090},
+		}
+		_, err := execute(Func(serve), "--server-host=example.com", "--bak-port=9090")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, served); diff != "" {
+			t.Errorf("diff(-want +got):\n%v", diff)
+		}
+	}
+}
+
+type mirrorOptions struct {
+	Host string `cli:"alias=h2"`
+}
+
+type mirrorsOptions struct {
+	A, B mirrorOptions
+}
+
+var mirrored *mirrorsOptions
+
+func mirror(opts *mirrorsOptions) {
+	mirrored = opts
+}
+
+func TestNestedAliases(t *testing.T) {
+	// Aliases are prefixed (like names), so that options structs can be reused.
+	_, err := execute(Func(mirror), "--a-h2=a.example.com", "--b-host=b.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &mirrorsOptions{
+		A: mirrorOptions{Host: "a.example.com"},
+		B: mirrorOptions{Host: "b.example.com"},
+	}
+	if diff := cmp.Diff(want, mirrored); diff != "" {
+		t.Errorf("diff(-want +got):\n%v", diff)
+	}
+}
+
+type Logging struct {
+	Debug   bool `cli:"short"`
+	LogFile string
+}
+
+type listOptions struct {
+	Logging
+	All    bool   `cli:"short"`
+	Format string `cli:"group=Output" default:"table"`
+	Color  bool   `cli:"group=Output"`
+}
+
+var listed *listOptions
+
+func list(opts *listOptions) {
+	listed = opts
+}
+
+func TestFlagGroups(t *testing.T) {
+	want := `Usage:
+  list [flags]
+
+Flags:
+  -a, --all   
+  -h, --help  help for list
+
+Logging Flags:
+  -d, --debug            
+      --log-file string  
+
+Output:
+      --format string (default table)  
+      --color
+`
+	got, err := execute(Func(list), "--help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff(-want +got):\n%v", diff)
+	}
+	// Embedded options structs are not prefixed (unlike nested ones).
+	if _, err := execute(Func(list), "--debug", "--log-file=list.log"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Logging{Debug: true, LogFile: "list.log"}); listed.Logging != want {
+		t.Errorf("list(--debug --log-file=list.log).Logging = %+v, want %+v", listed.Logging, want)
+	}
+}
+
+type loginOptions struct {
+	User      string `cli:"together=creds"`
+	Password  string `cli:"together=creds"`
+	Token     string `cli:"exclusive=auth"`
+	Anonymous bool   `cli:"exclusive=auth"`
+}
+
+func login(*loginOptions) {}
+
+func TestConstraints(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{
+			args: []string{"--user=u", "--password=p"},
+		},
+		{
+			args:    []string{"--user=u"},
+			wantErr: "if any flags in the group [user password] are set they must all be set; missing [password]",
+		},
+		{
+			args:    []string{"--token=t", "--anonymous"},
+			wantErr: "if any flags in the group [token anonymous] are set none of the others can be; [anonymous token] were all set",
+		},
+	}
+	for _, test := range tests {
+		_, err := execute(Func(login), test.args...)
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != test.wantErr {
+			t.Errorf("login(%v) = %q, want %q", test.args, gotErr, test.wantErr)
+		}
+	}
+}
+
+type formatOptions struct {
+	JSON bool `cli:"exclusive=fmt"`
+	YAML bool `cli:"exclusive=fmt"`
+}
+
+type convertOptions struct {
+	In, Out formatOptions
+}
+
+func convert(*convertOptions) {}
+
+func TestNestedConstraints(t *testing.T) {
+	// Constraints are scoped (like names), so that options structs can be reused.
+	if _, err := execute(Func(convert), "--in-json", "--out-yaml"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := execute(Func(convert), "--in-json", "--in-yaml")
+	want := "if any flags in the group [in-json in-yaml] are set none of the others can be; [in-json in-yaml] were all set"
+	if err == nil || err.Error() != want {
+		t.Errorf("convert(--in-json --in-yaml) = %v, want %q", err, want)
+	}
+	got, err := execute(Func(convert), "--help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  at most one of --in-json, --in-yaml\n",
+		"  at most one of --out-json, --out-yaml\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("convert(--help) = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+type copyOptions struct {
+	Dest     string   `cli:"nonempty,dir,isdir"`
+	Jobs     int      `cli:"min=1,max=8" default:"4"`
+	Suffixes []string `cli:"pattern=^\\.[a-z]+$"`
+}
+
+func copyFiles(*copyOptions) {}
+
+func TestValidation(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		file = filepath.Join(dir, "file")
+	)
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{
+			args: []string{"--dest", dir, "--suffixes=.go,.mod"},
+		},
+		{
+			args:    nil,
+			wantErr: "--dest violates nonempty: must not be empty",
+		},
+		{
+			args:    []string{"--dest", file},
+			wantErr: fmt.Sprintf("--dest=%q violates isdir: not a directory", file),
+		},
+		{
+			args:    []string{"--dest", dir, "--jobs=0"},
+			wantErr: `--jobs="0" violates min=1: must be at least 1`,
+		},
+		{
+			args:    []string{"--dest", dir, "--jobs=9"},
+			wantErr: `--jobs="9" violates max=8: must be at most 8`,
+		},
+		{
+			args:    []string{"--dest", dir, "--suffixes=.go,mod"},
+			wantErr: `--suffixes="mod" violates pattern=^\.[a-z]+$: must match ^\.[a-z]+$`,
+		},
+	}
+	for _, test := range tests {
+		_, err := execute(Func(copyFiles), test.args...)
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+			if uerr := new(usageError); !errors.As(err, &uerr) {
+				t.Errorf("copyFiles(%v) = %v, want usage error", test.args, err)
+			}
+		}
+		if gotErr != test.wantErr {
+			t.Errorf("copyFiles(%v) = %q, want %q", test.args, gotErr, test.wantErr)
+		}
+	}
+}
+
+type codeOptions struct {
+	Code string `cli:"pattern=^[a-z]{1,3}$"`
+}
+
+func code(*codeOptions) {}
+
+func TestUnknownTagKey(t *testing.T) {
+	// Commas can't be escaped, so this would otherwise validate ^[a-z]{1 instead.
+	defer func() {
+		want := `unknown cli tag key "3}$"`
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), want) {
+			t.Errorf("code(--help) panic = %v, want it to contain %q", r, want)
+		}
+	}()
+	_, _ = execute(Func(code), "--help")
+}
+
+type initOptions struct {
+	Repo string `cli:"dir"` // only completes directories, doesn't validate
+}
+
+func initRepo(*initOptions) {}
+
+func TestDirCompletionOnly(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "new")
+	if _, err := execute(Func(initRepo), "--repo", repo); err != nil {
+		t.Errorf("initRepo(--repo %v) = %v, want nil", repo, err)
+	}
+}
+
+type remote struct {
+	URL string
+}
+
+func (r *remote) Validate() error {
+	if !strings.HasPrefix(r.URL, "https://") {
+		return errors.New("--url must be an https URL")
+	}
+	return nil
+}
+
+type pushOptions struct {
+	Force, DryRun bool
+}
+
+func (opts *pushOptions) Validate(ctx context.Context) error {
+	if opts.Force && opts.DryRun {
+		return ErrUsage(errors.New("--force is meaningless with --dry-run"))
+	}
+	return nil
+}
+
+func (r *remote) Push(opts *pushOptions) {}
+
+type legacyOptions struct {
+	Verbose bool `cli:"deprecated"`
+}
+
+func (l *linter) Legacy(opts *legacyOptions) {}
+
+func TestDefaultDeprecation(t *testing.T) {
+	got, err := execute(Struct[linter](), "legacy", "--verbose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Flag --verbose has been deprecated, " + internal.DefaultDeprecation; !strings.Contains(got, want) {
+		t.Errorf("linter(legacy --verbose) = %q, want it to contain %q", got, want)
+	}
+	rmd := &internal.RawMetadata{
+		Children: map[string]*internal.RawMetadata{
+			"github.com/avamsi/climate": {
+				Children: map[string]*internal.RawMetadata{
+					"linter": {
+						Children: map[string]*internal.RawMetadata{
+							"Legacy": {Directives: map[string]string{"deprecated": ""}},
+						},
+					},
+				},
+			},
+		},
+	}
+	cmd := build(Struct[linter](), []func(*internal.RunOptions){WithMetadata(rmd.Encode())})
+	sub, _, err := cmd.delegate.Find([]string{"legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Deprecated != internal.DefaultDeprecation {
+		t.Errorf("linter(legacy).Deprecated = %q, want %q", sub.Deprecated, internal.DefaultDeprecation)
+	}
+}
+
+type linter struct{}
+
+var validated string
+
+// Validate the given file (a subcommand, as it's not a validation hook).
+func (l *linter) Validate(ctx context.Context, path string) error {
+	validated = path
+	return nil
+}
+
+type fixOptions struct {
+	DryRun bool
+}
+
+// Validate is not a validation hook either, so it's just ignored.
+func (opts *fixOptions) Validate(strict bool) bool {
+	return strict
+}
+
+func (l *linter) Fix(opts *fixOptions) {}
+
+func TestValidateNonHook(t *testing.T) {
+	if _, err := execute(Struct[linter](), "validate", "main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if validated != "main.go" {
+		t.Errorf("linter(validate main.go) validated %q, want %q", validated, "main.go")
+	}
+	if _, err := execute(Struct[linter](), "fix", "--dry-run"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateHook(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{
+			args: []string{"--url=https://example.com", "push", "--force"},
+		},
+		{
+			args:    []string{"push"},
+			wantErr: "--url must be an https URL",
+		},
+		{
+			args:    []string{"--url=https://example.com", "push", "--force", "--dry-run"},
+			wantErr: "--force is meaningless with --dry-run",
+		},
+		{
+			args:    []string{"validate"},
+			wantErr: `unknown command "validate" for "remote"`,
+		},
+	}
+	for _, test := range tests {
+		_, err := execute(Struct[remote](), test.args...)
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != test.wantErr {
+			t.Errorf("remote(%v) = %q, want %q", test.args, gotErr, test.wantErr)
+		}
+	}
+}
+
+type dialOptions struct {
+	DryRunMode bool `cli:"name=dry-run"`
+	IPv6       bool `cli:"name=ipv6,short"`
+	TimeoutMS  int  `cli:"name=timeoutMs"`
+}
+
+var dialed *dialOptions
+
+func dial(opts *dialOptions) {
+	dialed = opts
+}
+
+func TestExplicitNames(t *testing.T) {
+	{
+		want := `Usage:
+  dial [flags]
+
+Flags:
+      --dry-run        
+  -i, --ipv6           
+      --timeoutMs int  
+  -h, --help           help for dial
+`
+		got, err := execute(Func(dial), "--help")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("diff(-want +got):\n%v", diff)
+		}
+	}
+	{
+		want := &dialOptions{DryRunMode: true, IPv6: true, TimeoutMS: 42}
+		_, err := execute(Func(dial), "--dry_run", "--ipv6", "--timeoutMs=42")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, dialed); diff != "" {
+			t.Errorf("diff(-want +got):\n%v", diff)
+		}
+	}
+}
+
+type network struct{}
+
+type pingOptions struct {
+	TimeoutMs int
+	Größe     int `cli:"name=size"` // only the explicit name needs to be ASCII
+}
+
+var pinged *pingOptions
+
+func (n *network) Dial(opts *dialOptions) {
+	dialed = opts
+}
+
+func (n *network) Ping(opts *pingOptions) {
+	pinged = opts
+}
+
+func TestExplicitNamesScope(t *testing.T) {
+	// dial's explicit --timeoutMs doesn't turn off normalization for ping's.
+	if _, err := execute(Struct[network](), "ping", "--timeoutMs=5", "--size=64"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&pingOptions{TimeoutMs: 5, Größe: 64}); !cmp.Equal(want, pinged) {
+		t.Errorf("network(ping --timeoutMs=5 --size=64) = %+v, want %+v", pinged, want)
+	}
+	if _, err := execute(Struct[network](), "dial", "--timeoutMs=5"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&dialOptions{TimeoutMS: 5}); !cmp.Equal(want, dialed) {
+		t.Errorf("network(dial --timeoutMs=5) = %+v, want %+v", dialed, want)
+	}
+}
+
+func (r *remote) CompleteURL(toComplete string) []string {
+	return []string{"https://example.com"}
+}
+
+func (r *remote) CompletePush(ctx context.Context, args []string, toComplete string) []string {
+	return []string{"main", "dev"}
+}
+
+type todo struct {
+	Task string
+}
+
+type completeOptions struct {
+	All bool
+}
+
+var completed *completeOptions
+
+// CompleteTask is a subcommand (despite the name), as it's not a completion func.
+func (td *todo) CompleteTask(opts *completeOptions) {
+	completed = opts
+}
+
+func TestCompanionLookalike(t *testing.T) {
+	if _, err := execute(Struct[todo](), "completetask", "--all"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&completeOptions{All: true}); !cmp.Equal(want, completed) {
+		t.Errorf("todo(completetask --all) = %+v, want %+v", completed, want)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"__complete", "--url", ""},
+			want: "https://example.com\n:4\n",
+		},
+		{
+			args: []string{"__complete", "push", ""},
+			want: "main\ndev\n:4\n",
+		},
+		{
+			// Companions are not subcommands.
+			args: []string{"__complete", ""},
+			want: "completion\tGenerate the autocompletion script for the specified shell\n" +
+				"help\tHelp about any command\npush\n:4\n",
+		},
+	}
+	for _, test := range tests {
+		got, err := execute(Struct[remote](), test.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Cobra prints the completion directive as well, skip over it.
+		got, _, _ = strings.Cut(got, "Completion ended with directive")
+		if got != test.want {
+			t.Errorf("remote(%v) = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+func TestCompletionInstaller(t *testing.T) {
+	var (
+		home = t.TempDir()
+		opts = &internal.RunOptions{CompletionInstaller: true}
+		path = filepath.Join(home, "fish", "completions", "remote.fish")
+	)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	tests := []struct {
+		args       []string
+		want       string
+		wantExists bool
+	}{
+		{
+			args: []string{"completion", "install", "fish", "--dry-run"},
+			want: fmt.Sprintf("Would install fish completion for remote in %v\n", path),
+		},
+		{
+			args:       []string{"completion", "install", "fish"},
+			want:       fmt.Sprintf("Installed fish completion for remote in %v\n", path),
+			wantExists: true,
+		},
+		{
+			args:       []string{"completion", "uninstall", "fish", "--dry-run"},
+			want:       fmt.Sprintf("Would uninstall fish completion for remote from %v\n", path),
+			wantExists: true,
+		},
+		{
+			args: []string{"completion", "uninstall", "fish"},
+			want: fmt.Sprintf("Uninstalled fish completion for remote from %v\n", path),
+		},
+		{
+			args: []string{"completion", "uninstall", "fish"},
+			want: fmt.Sprintf("fish completion for remote is not installed in %v\n", path),
+		},
+	}
+	for _, test := range tests {
+		got, err := executeWithOptions(Struct[remote](), opts, test.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("remote(%v) = %q, want %q", test.args, got, test.want)
+		}
+		_, err = os.Stat(path)
+		if exists := err == nil; exists != test.wantExists {
+			t.Errorf("remote(%v): exists(%v) = %v, want %v", test.args, path, exists, test.wantExists)
+		}
+	}
+}
+
+func TestGenerateCompletion(t *testing.T) {
+	got, err := execute(Func(serve), "--generate-completion=fish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# fish completion for serve"; !strings.HasPrefix(got, want) {
+		t.Errorf("serve(--generate-completion=fish) = %q..., want %q...", got[:len(want)], want)
+	}
+}
+
+func TestManPages(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := execute(Struct[remote](), "man", dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"remote.1", "remote-push.1"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "remote-push.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`.TH "REMOTE-PUSH" "1"`,
+		".SH NAME\nremote\\-push \\- ",
+		".SH OPTIONS\n",
+		`\-\-force`,
+		".SH GLOBAL OPTIONS\n",
+		`\-\-url`,
+		`.SH SEE ALSO` + "\n" + `\fBremote\fP(1)`,
+	} {
+		if got := string(b); !strings.Contains(got, want) {
+			t.Errorf("man page does not contain %q:\n%v", want, got)
+		}
+	}
+	// Library generation matches the man subcommand (modulo the man command
+	// itself, which is hidden).
+	libDir := t.TempDir()
+	if err := GenerateManPages(Struct[remote](), libDir); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := os.ReadFile(filepath.Join(libDir, "remote-push.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(b), string(lib)); diff != "" {
+		t.Errorf("GenerateManPages() diff (-man +lib):\n%v", diff)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	if err := GenerateMarkdown(Struct[remote](), dir); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "remote-push.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# remote push\n",
+		"## Usage\n\n```\nremote push [flags]\n```\n",
+		"## Flags\n\n| Flag | Type | Default | Description |\n",
+		"| `--force` |",
+		"## Global Flags\n",
+		"| `--url` | string |",
+		"## See Also\n\n- [`remote`](remote.md)",
+	} {
+		if got := string(b); !strings.Contains(got, want) {
+			t.Errorf("remote-push.md does not contain %q:\n%v", want, got)
+		}
+	}
+	// Running with the hidden flag (see cligen docs) generates the exact same
+	// docs (instead of running the command).
+	flagDir := t.TempDir()
+	if _, err := execute(Struct[remote](), "--generate-markdown", flagDir); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		want, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(flagDir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(want), string(got)); diff != "" {
+			t.Errorf("%v diff (-want +got):\n%v", e.Name(), diff)
+		}
+	}
+}
+
+func clone(dir string) {}
+
+func TestDescribe(t *testing.T) {
+	spec := Describe(Struct[remote]())
+	var push *CommandSpec
+	for _, sub := range spec.Commands {
+		if sub.Name == "push" {
+			push = sub
+		}
+	}
+	if push == nil {
+		t.Fatalf("Describe(remote).Commands = %v, want push", spec.Commands)
+	}
+	wantFlags := []FlagSpec{
+		{Name: "force", Type: "bool", Default: "false"},
+		{Name: "dry-run", Type: "bool", Default: "false"},
+		{Name: "help", Shorthand: "h", Type: "bool", Default: "false", Usage: "help for push"},
+	}
+	if diff := cmp.Diff(wantFlags, push.Flags); diff != "" {
+		t.Errorf("Describe(remote push).Flags diff (-want +got):\n%v", diff)
+	}
+	// Positional args are validated only when actually running the command.
+	got, err := execute(Func(clone), "--help-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotSpec CommandSpec
+	if err := json.Unmarshal([]byte(got), &gotSpec); err != nil {
+		t.Fatal(err)
+	}
+	wantParams := []ParamSpec{{Name: "args", Type: RequiredParam}}
+	if diff := cmp.Diff(wantParams, gotSpec.Params); diff != "" {
+		t.Errorf("clone(--help-json).Params diff (-want +got):\n%v", diff)
+	}
+}
+
+type branch struct{}
+
+func (b *branch) Delete() {}
+
+func TestTheme(t *testing.T) {
+	plain, err := execute(Struct[remote](), "push", "--help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &internal.RunOptions{Width: 80, Theme: &DefaultTheme}
+	t.Setenv("FORCE_COLOR", "1")
+	{
+		got, err := executeWithOptions(Struct[remote](), opts, "push", "--help")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"\x1b[1mUsage:\x1b[0m\n",
+			"\x1b[1mGlobal Flags:\x1b[0m\n",
+			"\x1b[36m-h\x1b[0m, \x1b[36m--help\x1b[0m",
+			"\x1b[33mstring\x1b[0m",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("remote(push --help) = %q, want it to contain %q", got, want)
+			}
+		}
+		// Styles don't affect the alignment of the tables.
+		if diff := cmp.Diff(plain, sgrRegexp.ReplaceAllString(got, "")); diff != "" {
+			t.Errorf("diff(-plain +unstyled):\n%v", diff)
+		}
+	}
+	{
+		got, _ := executeWithOptions(Struct[remote](Struct[branch]()), opts, "branch", "delet")
+		for _, want := range []string{
+			"\x1b[1;31mError:\x1b[0m unknown command",
+			"\x1b[1mDid you mean this?\x1b[0m\n\t\x1b[32mdelete\x1b[0m\n",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("remote(branch delet) = %q, want it to contain %q", got, want)
+			}
+		}
+	}
+	{
+		// NO_COLOR takes precedence over FORCE_COLOR.
+		t.Setenv("NO_COLOR", "1")
+		got, err := executeWithOptions(Struct[remote](), opts, "push", "--help")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(plain, got); diff != "" {
+			t.Errorf("diff(-plain +got):\n%v", diff)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	opts := &internal.RunOptions{
+		Width: 80,
+		UsageTemplate: `{{with describe .}}{{.Path}}{{range .Params}} <{{.Name}}: {{.Type}}>{{end}}
+{{range .Flags}}{{if not .Hidden}}  --{{.Name}} ({{.Type}}){{if .Rules}} {{.Rules}}{{end}}
+{{end}}{{end}}{{end}}`,
+		HelpTemplate: `{{heading $ "Help for"}} {{.UseLine}}
+{{.UsageString}}`,
+	}
+	want := `Help for copyfiles [flags]
+copyfiles
+  --dest (string) [nonempty isdir]
+  --jobs (int64) [min=1 max=8]
+  --suffixes (stringSlice) [pattern=^\.[a-z]+$]
+  --help (bool)
+`
+	got, err := executeWithOptions(Func(copyFiles), opts, "--help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff(-want +got):\n%v", diff)
+	}
+}
+
+func TestMissingMetadataHook(t *testing.T) {
+	rmd := &internal.RawMetadata{
+		Children: map[string]*internal.RawMetadata{
+			"github.com/avamsi/climate": {
+				Children: map[string]*internal.RawMetadata{
+					"remote": {Doc: "Manage remotes."},
+				},
+			},
+		},
+	}
+	var got []string
+	build(Struct[remote](Struct[branch]()),
+		[]func(*internal.RunOptions){
+			WithMetadata(rmd.Encode()),
+			WithMissingMetadataHook(func(missing []string) { got = missing }),
+		})
+	want := []string{
+		"github.com/avamsi/climate.pushOptions", // options structs are looked up too
+		"github.com/avamsi/climate.branch",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("missing diff (-want +got):\n%v", diff)
+	}
+	// Unnamed (nested) options structs have no metadata, so aren't missing.
+	climate := rmd.Children["github.com/avamsi/climate"]
+	climate.Children["tunnel"] = &internal.RawMetadata{}
+	climate.Children["tunnelOptions"] = &internal.RawMetadata{}
+	got = nil
+	build(Func(tunnel),
+		[]func(*internal.RunOptions){
+			WithMetadata(rmd.Encode()),
+			WithMissingMetadataHook(func(missing []string) { got = missing }),
+		})
+	if got != nil {
+		t.Errorf("missing = %v, want none", got)
+	}
+}
+
+type tunnelOptions struct {
+	Server struct {
+		Host string
+	}
+}
+
+func tunnel(*tunnelOptions) {}
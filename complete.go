@@ -0,0 +1,107 @@
+package climate
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/avamsi/ergo/assert"
+	"github.com/spf13/cobra"
+)
+
+var stringSliceType = reflect.TypeFor[[]string]()
+
+// isCompletionFunc reports whether the given func type conforms to
+//
+//	func([ctx context.Context], toComplete string) []string
+//
+// or, if withArgs is true,
+//
+//	func([ctx context.Context], args []string, toComplete string) []string
+func isCompletionFunc(t reflect.Type, withArgs bool) bool {
+	i, n := 0, t.NumIn()
+	if i < n && typeIsContext(t.In(i)) {
+		i++
+	}
+	if withArgs {
+		if i >= n || t.In(i) != stringSliceType {
+			return false
+		}
+		i++
+	}
+	return i+1 == n && t.In(i).Kind() == reflect.String &&
+		t.NumOut() == 1 && t.Out(0) == stringSliceType
+}
+
+// completionFunc adapts the given method, which must be a completion func (see
+// isCompletionFunc), to a cobra.CompletionFunc (that turns off file completion).
+func completionFunc(m reflect.Value, withArgs bool) cobra.CompletionFunc {
+	t := m.Type()
+	assert.Truef(isCompletionFunc(t, withArgs),
+		"not func([context.Context], [[]string], string) []string: %v", t)
+	inCtx := t.NumIn() > 0 && typeIsContext(t.In(0))
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var in []reflect.Value
+		if inCtx {
+			in = append(in, reflect.ValueOf(cmd.Context()))
+		}
+		if withArgs {
+			in = append(in, reflect.ValueOf(args))
+		}
+		in = append(in, reflect.ValueOf(toComplete))
+		out := m.Call(in)
+		return out[0].Interface().([]string), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// companion returns the Complete<target> method of the given struct pointer if
+// it's a completion func (see isCompletionFunc) for the target field or, if
+// withArgs is true, method (and the zero Value otherwise).
+func companion(ptr reflect.Value, target string, withArgs bool) reflect.Value {
+	m := ptr.MethodByName("Complete" + target)
+	if !m.IsValid() || !isCompletionFunc(m.Type(), withArgs) {
+		return reflect.Value{}
+	}
+	return m
+}
+
+// isCompanion reports whether the named method of the given struct pointer is a
+// companion of (i.e., completes) one of its fields or other methods, rather
+// than a subcommand itself (which it still is, if its signature doesn't match).
+func isCompanion(ptr reflect.Value, name string) bool {
+	target, ok := strings.CutPrefix(name, "Complete")
+	if !ok || target == "" {
+		return false
+	}
+	if _, ok := ptr.Elem().Type().FieldByName(target); ok && companion(ptr, target, false).IsValid() {
+		return true
+	}
+	_, ok = ptr.Type().MethodByName(target)
+	return ok && companion(ptr, target, true).IsValid()
+}
+
+// fileExtensions parses the given (pipe separated) file patterns ("*.go|*.mod",
+// say) into the extensions Cobra expects ("go" and "mod").
+func fileExtensions(v string) []string {
+	var exts []string
+	for _, ext := range strings.Split(v, "|") {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), "*")
+		if ext = strings.TrimPrefix(ext, "."); ext != "" {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// fileCompletionFunc returns a cobra.CompletionFunc that completes (only)
+// directories if dir is true, or files with any of the given extensions.
+func fileCompletionFunc(dir bool, exts []string) cobra.CompletionFunc {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		if dir {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+		if len(exts) > 0 {
+			return exts, cobra.ShellCompDirectiveFilterFileExt
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
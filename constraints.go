@@ -0,0 +1,116 @@
+package climate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type constraintKind struct {
+	tag, annotation string
+	// describe describes the constraint for the given (already formatted)
+	// flags, for --help.
+	describe func(flags string) string
+	mark     func(cmd *cobra.Command, names ...string)
+}
+
+// constraintKinds are the kinds of constraints that can be declared on a group
+// of flags (using the "cli" tags), all of which are enforced by Cobra.
+var constraintKinds = []constraintKind{
+	{
+		tag:        "exclusive",
+		annotation: "climate_annotation_exclusive",
+		describe: func(flags string) string {
+			return "at most one of " + flags
+		},
+		mark: (*cobra.Command).MarkFlagsMutuallyExclusive,
+	},
+	{
+		tag:        "oneof",
+		annotation: "climate_annotation_oneof",
+		describe: func(flags string) string {
+			return "exactly one of " + flags
+		},
+		mark: func(cmd *cobra.Command, names ...string) {
+			cmd.MarkFlagsOneRequired(names...)
+			cmd.MarkFlagsMutuallyExclusive(names...)
+		},
+	},
+	{
+		tag:        "anyof",
+		annotation: "climate_annotation_anyof",
+		describe: func(flags string) string {
+			return "at least one of " + flags
+		},
+		mark: (*cobra.Command).MarkFlagsOneRequired,
+	},
+	{
+		tag:        "together",
+		annotation: "climate_annotation_together",
+		describe: func(flags string) string {
+			return "all or none of " + flags
+		},
+		mark: (*cobra.Command).MarkFlagsRequiredTogether,
+	},
+}
+
+type constraint struct {
+	kind  *constraintKind
+	names []string
+}
+
+func (c *constraint) String() string {
+	flags := make([]string, len(c.names))
+	for i, name := range c.names {
+		flags[i] = "--" + name
+	}
+	return c.kind.describe(strings.Join(flags, ", "))
+}
+
+// constraints returns the flag constraints declared in the given flag set, in
+// the order of their first appearance.
+func constraints(fset *pflag.FlagSet) []*constraint {
+	var (
+		cs   []*constraint
+		seen = map[string]*constraint{}
+	)
+	fset.VisitAll(func(f *pflag.Flag) {
+		for i := range constraintKinds {
+			kind := &constraintKinds[i]
+			for _, group := range f.Annotations[kind.annotation] {
+				key := kind.tag + "=" + group
+				c, ok := seen[key]
+				if !ok {
+					c = &constraint{kind: kind}
+					seen[key] = c
+					cs = append(cs, c)
+				}
+				c.names = append(c.names, f.Name)
+			}
+		}
+	})
+	return cs
+}
+
+// markConstraints marks the flag constraints declared in the given flag set
+// (which must belong to cmd) for Cobra to enforce.
+func markConstraints(cmd *cobra.Command, fset *pflag.FlagSet) {
+	for _, c := range constraints(fset) {
+		c.kind.mark(cmd, c.names...)
+	}
+}
+
+func constraintUsages(fset *pflag.FlagSet, theme Theme) string {
+	cs := constraints(fset)
+	if len(cs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%v\n", style(theme.Heading, "Constraints:"))
+	for _, c := range cs {
+		fmt.Fprintf(&b, "  %v\n", c)
+	}
+	return b.String()
+}
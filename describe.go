@@ -0,0 +1,152 @@
+package climate
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/avamsi/climate/internal"
+)
+
+const (
+	paramName = "climate_annotation_param_name"
+	paramType = "climate_annotation_param_type"
+)
+
+// CommandSpec describes a command (and, recursively, its subcommands), see
+// Describe.
+type CommandSpec struct {
+	Name        string         `json:"name"`
+	Path        string         `json:"path"`
+	Usage       string         `json:"usage"`
+	Aliases     []string       `json:"aliases,omitempty"`
+	Short       string         `json:"short,omitempty"`
+	Long        string         `json:"long,omitempty"`
+	Examples    []string       `json:"examples,omitempty"`
+	Group       string         `json:"group,omitempty"`
+	Params      []ParamSpec    `json:"params,omitempty"`
+	Flags       []FlagSpec     `json:"flags,omitempty"`
+	Constraints []string       `json:"constraints,omitempty"`
+	Hidden      bool           `json:"hidden,omitempty"`
+	Deprecated  string         `json:"deprecated,omitempty"`
+	Commands    []*CommandSpec `json:"commands,omitempty"`
+}
+
+// ParamSpec describes a positional parameter of a command.
+type ParamSpec struct {
+	Name string    `json:"name"`
+	Type ParamType `json:"type"`
+}
+
+// ParamType is the type of a positional parameter (see ParamSpec), which is
+// encoded as its name ("required", say) in JSON.
+type ParamType = internal.ParamType
+
+// ParamTypes, by the Go type of the parameter.
+const (
+	RequiredParam        = internal.RequiredParam        // string
+	OptionalParam        = internal.OptionalParam        // *string
+	FixedLengthParam     = internal.FixedLengthParam     // [N]string
+	ArbitraryLengthParam = internal.ArbitraryLengthParam // []string
+)
+
+// FlagSpec describes a flag of a command. Persistent flags are only described
+// on the command that declares them (and are inherited by its subcommands).
+type FlagSpec struct {
+	Name       string   `json:"name"`
+	Shorthand  string   `json:"shorthand,omitempty"`
+	Aliases    []string `json:"aliases,omitempty"`
+	Type       string   `json:"type"`
+	Default    string   `json:"default"`
+	Usage      string   `json:"usage,omitempty"`
+	Group      string   `json:"group,omitempty"`
+	Rules      []string `json:"rules,omitempty"`
+	Required   bool     `json:"required,omitempty"`
+	Persistent bool     `json:"persistent,omitempty"`
+	Hidden     bool     `json:"hidden,omitempty"`
+	Deprecated string   `json:"deprecated,omitempty"`
+}
+
+func describeFlag(f *pflag.Flag, persistent bool) FlagSpec {
+	_, usage := pflag.UnquoteUsage(f)
+	spec := FlagSpec{
+		Name:       f.Name,
+		Shorthand:  f.Shorthand,
+		Aliases:    f.Annotations[flagAliases],
+		Type:       f.Value.Type(),
+		Default:    f.DefValue,
+		Usage:      usage,
+		Rules:      f.Annotations[flagRules],
+		Persistent: persistent,
+		Hidden:     f.Hidden,
+		Deprecated: f.Deprecated,
+	}
+	if group, ok := f.Annotations[flagGroup]; ok {
+		spec.Group = group[0]
+	}
+	if required, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok {
+		spec.Required = required[0] == "true"
+	}
+	return spec
+}
+
+func describe(cmd *cobra.Command) *CommandSpec {
+	initDefaults(cmd)
+	spec := &CommandSpec{
+		Name:       cmd.Name(),
+		Path:       cmd.CommandPath(),
+		Usage:      cmd.UseLine(),
+		Aliases:    cmd.Aliases,
+		Short:      cmd.Short,
+		Long:       strings.TrimSpace(cmd.Long),
+		Group:      cmd.GroupID,
+		Hidden:     cmd.Hidden,
+		Deprecated: cmd.Deprecated,
+	}
+	for _, example := range strings.Split(cmd.Example, "\n") {
+		if example = strings.TrimSpace(example); example != "" {
+			spec.Examples = append(spec.Examples, example)
+		}
+	}
+	if name, ok := cmd.Annotations[paramName]; ok {
+		t, _ := strconv.Atoi(cmd.Annotations[paramType])
+		spec.Params = append(spec.Params, ParamSpec{name, ParamType(t)})
+	}
+	persistent := cmd.PersistentFlags()
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		spec.Flags = append(spec.Flags, describeFlag(f, persistent.Lookup(f.Name) != nil))
+	})
+	for _, c := range constraints(cmd.LocalFlags()) {
+		spec.Constraints = append(spec.Constraints, c.String())
+	}
+	for _, sub := range cmd.Commands() {
+		spec.Commands = append(spec.Commands, describe(sub))
+	}
+	return spec
+}
+
+// addHelpJSONFlag adds a hidden --help-json flag to root, which prints the
+// full command tree (see Describe) as JSON (instead of running root).
+func addHelpJSONFlag(root *cobra.Command) {
+	const name = "help-json"
+	var helpJSON bool
+	root.Flags().BoolVar(&helpJSON, name, false, "print the full command tree as JSON")
+	_ = root.Flags().MarkHidden(name)
+	intercept(root, func() bool { return helpJSON }, func(cmd *cobra.Command) error {
+		e := json.NewEncoder(cmd.OutOrStdout())
+		e.SetEscapeHTML(false)
+		e.SetIndent("", "  ")
+		return e.Encode(describe(cmd))
+	})
+}
+
+// Describe describes the full command tree of the given plan (including the
+// hidden and deprecated commands and flags), for tooling built on top of it
+// (docs, linting flag names, generating wrappers etc.). The same description is
+// available as JSON with the hidden --help-json flag (on the root command).
+func Describe(p internal.Plan, mods ...func(*internal.RunOptions)) *CommandSpec {
+	return describe(&build(p, mods).delegate)
+}
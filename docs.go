@@ -0,0 +1,53 @@
+package climate
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// initDefaults adds the help and version flags to cmd (and the help and
+// completion commands, if cmd is the root), which Cobra otherwise only adds on
+// execution (and the flags only to the command being executed), so that the
+// commands are documented (and described) the same whether executed or not.
+func initDefaults(cmd *cobra.Command) {
+	if !cmd.HasParent() {
+		cmd.InitDefaultHelpCmd()
+		cmd.InitDefaultCompletionCmd()
+	}
+	cmd.InitDefaultHelpFlag()
+	cmd.InitDefaultVersionFlag()
+}
+
+// walkAvailable calls fn for cmd and all its available subcommands
+// (recursively), with Cobra's defaults added (see initDefaults).
+func walkAvailable(cmd *cobra.Command, fn func(*cobra.Command) error) error {
+	initDefaults(cmd)
+	if err := fn(cmd); err != nil {
+		return err
+	}
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() || sub.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		if err := walkAvailable(sub, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDocs writes docs for cmd and all its available subcommands to dir, one
+// file per command (named by file and rendered by page).
+func writeDocs(cmd *cobra.Command, dir string, file, page func(*cobra.Command) string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return walkAvailable(cmd, func(cmd *cobra.Command) error {
+		// #nosec G306 -- G306 expects 0o600 or less but 0o644 is fine here as
+		// the docs are not really sensitive (and are meant to be published).
+		path := filepath.Join(dir, file(cmd))
+		return os.WriteFile(path, []byte(page(cmd)), 0o644)
+	})
+}
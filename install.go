@@ -0,0 +1,276 @@
+package climate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type completionScript struct {
+	shell string
+	// path returns where the completion script for the named command should be
+	// installed (or, for PowerShell, the profile that should load it).
+	path func(name string) (string, error)
+	gen  func(root *cobra.Command, w io.Writer) error
+	// profile is true if path is a profile that loads the completion script
+	// (from the command itself) rather than where the script is written to.
+	profile bool
+	hint    string
+}
+
+// xdgDir returns the directory in the given XDG environment variable, falling
+// back to the given directory relative to the user's home directory.
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	return filepath.Join(home, fallback), err
+}
+
+var completionScripts = []*completionScript{
+	{
+		shell: "bash",
+		path: func(name string) (string, error) {
+			dir, err := xdgDir("XDG_DATA_HOME", ".local/share")
+			return filepath.Join(dir, "bash-completion", "completions", name), err
+		},
+		gen: func(root *cobra.Command, w io.Writer) error {
+			return root.GenBashCompletionV2(w, true)
+		},
+		hint: "Note: this requires the bash-completion package (v2.8 or newer).",
+	},
+	{
+		shell: "zsh",
+		path: func(name string) (string, error) {
+			dir := os.Getenv("ZDOTDIR")
+			if dir == "" {
+				var err error
+				if dir, err = os.UserHomeDir(); err != nil {
+					return "", err
+				}
+			}
+			return filepath.Join(dir, ".zfunc", "_"+name), nil
+		},
+		gen: func(root *cobra.Command, w io.Writer) error {
+			return root.GenZshCompletion(w)
+		},
+		hint: "Note: make sure the directory is in your fpath (before compinit is run).",
+	},
+	{
+		shell: "fish",
+		path: func(name string) (string, error) {
+			dir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+			return filepath.Join(dir, "fish", "completions", name+".fish"), err
+		},
+		gen: func(root *cobra.Command, w io.Writer) error {
+			return root.GenFishCompletion(w, true)
+		},
+	},
+	{
+		shell: "powershell",
+		path: func(string) (string, error) {
+			const profile = "Microsoft.PowerShell_profile.ps1"
+			if runtime.GOOS == "windows" {
+				home, err := os.UserHomeDir()
+				return filepath.Join(home, "Documents", "PowerShell", profile), err
+			}
+			dir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+			return filepath.Join(dir, "powershell", profile), err
+		},
+		gen: func(root *cobra.Command, w io.Writer) error {
+			return root.GenPowerShellCompletionWithDesc(w)
+		},
+		profile: true,
+	},
+}
+
+func lookupCompletionScript(shell string) (*completionScript, error) {
+	i := slices.IndexFunc(completionScripts, func(cs *completionScript) bool {
+		return cs.shell == shell
+	})
+	if i == -1 {
+		return nil, ErrUsage(fmt.Errorf("unsupported shell: %q", shell))
+	}
+	return completionScripts[i], nil
+}
+
+// profileLine returns the line that loads the completion script for the named
+// command in a PowerShell profile.
+func profileLine(name string) string {
+	return fmt.Sprintf("%v completion powershell | Out-String | Invoke-Expression", name)
+}
+
+func (cs *completionScript) install(root *cobra.Command, dryRun bool) error {
+	var (
+		name      = root.Name()
+		path, err = cs.path(name)
+		out       = root.OutOrStdout()
+	)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if !cs.profile {
+		if err := cs.gen(root, &b); err != nil {
+			return err
+		}
+	} else {
+		profile, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		line := profileLine(name)
+		if slices.Contains(strings.Split(string(profile), "\n"), line) {
+			fmt.Fprintf(out, "%v completion for %v is already installed in %v\n", cs.shell, name, path)
+			return nil
+		}
+		b.Write(profile)
+		if len(profile) > 0 && !bytes.HasSuffix(profile, []byte("\n")) {
+			b.WriteString("\n")
+		}
+		b.WriteString(line + "\n")
+	}
+	if dryRun {
+		fmt.Fprintf(out, "Would install %v completion for %v in %v\n", cs.shell, name, path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// #nosec G306 -- G306 expects 0o600 or less but 0o644 is fine here as the
+	// completion script (or the profile) is not really sensitive.
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Installed %v completion for %v in %v\n", cs.shell, name, path)
+	if cs.hint != "" {
+		fmt.Fprintln(out, cs.hint)
+	}
+	return nil
+}
+
+func (cs *completionScript) uninstall(root *cobra.Command, dryRun bool) error {
+	var (
+		name      = root.Name()
+		path, err = cs.path(name)
+		out       = root.OutOrStdout()
+	)
+	if err != nil {
+		return err
+	}
+	notInstalled := func() error {
+		fmt.Fprintf(out, "%v completion for %v is not installed in %v\n", cs.shell, name, path)
+		return nil
+	}
+	write := func() error { return os.Remove(path) }
+	if cs.profile {
+		profile, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return notInstalled()
+		}
+		if err != nil {
+			return err
+		}
+		var (
+			lines = strings.Split(string(profile), "\n")
+			line  = profileLine(name)
+		)
+		if !slices.Contains(lines, line) {
+			return notInstalled()
+		}
+		lines = slices.DeleteFunc(lines, func(l string) bool { return l == line })
+		write = func() error {
+			// #nosec G306 -- see install.
+			return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644)
+		}
+	} else if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return notInstalled()
+	}
+	if dryRun {
+		fmt.Fprintf(out, "Would uninstall %v completion for %v from %v\n", cs.shell, name, path)
+		return nil
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Uninstalled %v completion for %v from %v\n", cs.shell, name, path)
+	return nil
+}
+
+func completionInstallerCommand(use string, run func(*completionScript, *cobra.Command, bool) error) *cobra.Command {
+	var (
+		shells []string
+		dryRun bool
+	)
+	for _, cs := range completionScripts {
+		shells = append(shells, cs.shell)
+	}
+	help := fmt.Sprintf("%v the autocompletion script for the specified shell",
+		strings.ToUpper(use[:1])+use[1:])
+	cmd := &cobra.Command{
+		Use:       use + " <shell>",
+		Short:     help,
+		Long:      fmt.Sprintf("%v (one of %v).", help, strings.Join(shells, ", ")),
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: shells,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cs, err := lookupCompletionScript(args[0])
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return run(cs, cmd.Root(), dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print what would be done")
+	return cmd
+}
+
+// addCompletionInstaller adds install and uninstall subcommands to root's
+// completion command (if any).
+func addCompletionInstaller(root *cobra.Command) {
+	for _, c := range root.Commands() {
+		if c.Name() != "completion" {
+			continue
+		}
+		c.AddCommand(
+			completionInstallerCommand("install", (*completionScript).install),
+			completionInstallerCommand("uninstall", (*completionScript).uninstall),
+		)
+	}
+}
+
+// addCompletionFlag adds a hidden --generate-completion flag to root, which
+// prints the completion script for the given shell (instead of running root).
+// This is meant for roots without any subcommands, for which Cobra doesn't add
+// the completion command.
+func addCompletionFlag(root *cobra.Command) {
+	var (
+		shells []string
+		shell  string
+	)
+	for _, cs := range completionScripts {
+		shells = append(shells, cs.shell)
+	}
+	const name = "generate-completion"
+	root.Flags().StringVar(&shell, name, "", "print the completion script for the given `shell`")
+	_ = root.Flags().MarkHidden(name)
+	_ = root.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(shells, cobra.ShellCompDirectiveNoFileComp))
+	intercept(root, func() bool { return shell != "" }, func(cmd *cobra.Command) error {
+		cs, err := lookupCompletionScript(shell)
+		if err != nil {
+			return err
+		}
+		return cs.gen(cmd, cmd.OutOrStdout())
+	})
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Diff returns a human readable diff of the metadata entries (funcs, types,
+// fields etc.) that changed from old to new, one entry per line (along with
+// the changes, indented), or the empty string if there are no differences.
+func Diff(old, new *RawMetadata) string {
+	var b strings.Builder
+	diffChildren(&b, "", old, new)
+	return b.String()
+}
+
+func childPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func diffChildren(b *strings.Builder, path string, old, new *RawMetadata) {
+	names := slices.Sorted(maps.Keys(old.Children))
+	for name := range new.Children {
+		if _, ok := old.Children[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		var (
+			p        = childPath(path, name)
+			o, oldOK = old.Children[name]
+			n, newOK = new.Children[name]
+		)
+		switch {
+		case !oldOK:
+			fmt.Fprintf(b, "+ %v\n", p)
+		case !newOK:
+			fmt.Fprintf(b, "- %v\n", p)
+		default:
+			diffEntry(b, p, o, n)
+			diffChildren(b, p, o, n)
+		}
+	}
+}
+
+func diffValue(b *strings.Builder, field, old, new string) {
+	if old == new {
+		return
+	}
+	fmt.Fprintf(b, "    %v:\n", field)
+	if old != "" {
+		for _, line := range strings.Split(old, "\n") {
+			fmt.Fprintln(b, strings.TrimRight("      - "+line, " "))
+		}
+	}
+	if new != "" {
+		for _, line := range strings.Split(new, "\n") {
+			fmt.Fprintln(b, strings.TrimRight("      + "+line, " "))
+		}
+	}
+}
+
+func diffEntry(b *strings.Builder, path string, old, new *RawMetadata) {
+	var changes strings.Builder
+	diffValue(&changes, "doc", old.Doc, new.Doc)
+	diffValue(&changes, "comment", old.Comment, new.Comment)
+	diffValue(&changes, "params",
+		strings.Join(old.Params, ", "), strings.Join(new.Params, ", "))
+	diffValue(&changes, "examples",
+		strings.Join(old.Examples, "\n"), strings.Join(new.Examples, "\n"))
+	directives := slices.Sorted(maps.Keys(old.Directives))
+	for d := range new.Directives {
+		if _, ok := old.Directives[d]; !ok {
+			directives = append(directives, d)
+		}
+	}
+	slices.Sort(directives)
+	for _, d := range directives {
+		o, oldOK := old.Directives[d]
+		n, newOK := new.Directives[d]
+		if oldOK {
+			// Distinguish "present but empty" (like //cli:hidden) from absent.
+			o = strings.TrimSpace(directivePrefix + d + " " + o)
+		}
+		if newOK {
+			n = strings.TrimSpace(directivePrefix + d + " " + n)
+		}
+		diffValue(&changes, "directive "+d, o, n)
+	}
+	if changes.Len() > 0 {
+		fmt.Fprintf(b, "~ %v\n%v", path, changes.String())
+	}
+}
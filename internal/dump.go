@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Dump returns a human readable tree of the metadata (packages, types, funcs,
+// fields etc.), one entry per line (with its params, if any), followed by its
+// docs, directives and examples rendered (and indented) like in Go source.
+func Dump(rmd *RawMetadata) string {
+	var b strings.Builder
+	dumpChildren(&b, "", rmd)
+	return b.String()
+}
+
+func dumpChildren(b *strings.Builder, indent string, rmd *RawMetadata) {
+	for _, name := range slices.Sorted(maps.Keys(rmd.Children)) {
+		child := rmd.Children[name]
+		if child.Params != nil {
+			name += "(" + strings.Join(child.Params, ", ") + ")"
+		}
+		fmt.Fprintf(b, "%v%v\n", indent, name)
+		dumpEntry(b, indent+"  ", child)
+		dumpChildren(b, indent+"  ", child)
+	}
+}
+
+func dumpComment(b *strings.Builder, indent, s string) {
+	if s == "" {
+		return
+	}
+	for _, line := range strings.Split(s, "\n") {
+		fmt.Fprintf(b, "%v%v\n", indent, strings.TrimSpace("// "+line))
+	}
+}
+
+func dumpEntry(b *strings.Builder, indent string, rmd *RawMetadata) {
+	dumpComment(b, indent, rmd.Doc)
+	dumpComment(b, indent, rmd.Comment)
+	for _, d := range slices.Sorted(maps.Keys(rmd.Directives)) {
+		fmt.Fprintf(b, "%v%v\n", indent,
+			strings.TrimSpace(directivePrefix+d+" "+rmd.Directives[d]))
+	}
+	for _, e := range rmd.Examples {
+		fmt.Fprintf(b, "%v%vexample %v\n", indent, directivePrefix, e)
+	}
+}
@@ -3,9 +3,14 @@ package internal
 import (
 	"bytes"
 	"encoding/gob"
+	"encoding/json"
+	"fmt"
 	"go/ast"
 	"reflect"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/avamsi/ergo"
@@ -14,19 +19,47 @@ import (
 )
 
 // Note: it's important that all fields of RawMetadata be exported, otherwise
-// gob won't be able to encode / decode them correctly.
+// they won't be encoded / decoded correctly (as JSON or legacy gob).
 type RawMetadata struct {
-	Doc        string
-	Directives map[string]string
-	Comment    string
-	Params     []string
-	Children   map[string]*RawMetadata
+	Doc        string            `json:"doc,omitempty"`
+	Directives map[string]string `json:"directives,omitempty"`
+	Comment    string            `json:"comment,omitempty"`
+	Params     []string          `json:"params,omitempty"`
+	// Examples from //cli:example directives and godoc "Examples:" blocks
+	// (which can't be stored as Directives, as they can be repeated).
+	Examples []string                `json:"examples,omitempty"`
+	Children map[string]*RawMetadata `json:"children,omitempty"`
 }
 
-func DecodeAsRawMetadata(b []byte) *RawMetadata {
+// MetadataVersion is the version of the metadata format, to be bumped on any
+// backward incompatible change to RawMetadata.
+const MetadataVersion = 1
+
+// metadataHeader precedes the (JSON encoded) metadata, to tell it apart from
+// the legacy gob encoded metadata (and to version the format).
+const metadataHeader = "climate-metadata v"
+
+func DecodeAsRawMetadata(b []byte) (*RawMetadata, error) {
 	var rmd RawMetadata
-	assert.Nil(gob.NewDecoder(bytes.NewReader(b)).Decode(&rmd))
-	return &rmd
+	rest, ok := bytes.CutPrefix(b, []byte(metadataHeader))
+	if !ok {
+		// Metadata generated before versioning was gob encoded, and while not
+		// deterministic, it's still supported (for now).
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&rmd); err != nil {
+			return nil, fmt.Errorf("malformed metadata (neither versioned nor gob): %w", err)
+		}
+		return &rmd, nil
+	}
+	v, rest, _ := bytes.Cut(rest, []byte("\n"))
+	if version, err := strconv.Atoi(string(v)); err != nil || version != MetadataVersion {
+		return nil, fmt.Errorf(
+			"unsupported metadata version %q (want %v), regenerate it with a cligen matching climate",
+			v, MetadataVersion)
+	}
+	if err := json.Unmarshal(rest, &rmd); err != nil {
+		return nil, fmt.Errorf("malformed metadata (v%v): %w", MetadataVersion, err)
+	}
+	return &rmd, nil
 }
 
 const directivePrefix = "//cli:"
@@ -35,7 +68,7 @@ func (rmd *RawMetadata) SetDoc(doc *ast.CommentGroup) {
 	if doc == nil {
 		return
 	}
-	rmd.Doc = strings.TrimSpace(doc.Text())
+	rmd.Doc, rmd.Examples = cutExamples(strings.TrimSpace(doc.Text()))
 	rmd.Directives = map[string]string{}
 	for _, comment := range doc.List {
 		if !strings.HasPrefix(comment.Text, directivePrefix) {
@@ -43,6 +76,10 @@ func (rmd *RawMetadata) SetDoc(doc *ast.CommentGroup) {
 		}
 		d, value, _ := strings.Cut(comment.Text, " ")
 		d = strings.TrimPrefix(d, directivePrefix)
+		if d == "example" {
+			rmd.Examples = append(rmd.Examples, strings.TrimSpace(value))
+			continue
+		}
 		if _, ok := rmd.Directives[d]; ok {
 			ergo.Panicf("more than one %v directive: %v", d, litter.Sdump(doc))
 		}
@@ -50,6 +87,33 @@ func (rmd *RawMetadata) SetDoc(doc *ast.CommentGroup) {
 	}
 }
 
+// cutExamples cuts the (godoc style) "Examples:" block, i.e., an "Examples:"
+// paragraph followed by indented lines (one example each), out of doc.
+func cutExamples(doc string) (string, []string) {
+	var (
+		lines    = strings.Split(doc, "\n")
+		examples []string
+	)
+	for i, line := range lines {
+		if line != "Examples:" || (i > 0 && lines[i-1] != "") {
+			continue
+		}
+		j := i + 1
+		for ; j < len(lines); j++ {
+			line := lines[j]
+			if line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+				break
+			}
+			if line = strings.TrimSpace(line); line != "" {
+				examples = append(examples, line)
+			}
+		}
+		rest := append(lines[:i:i], lines[j:]...)
+		return strings.TrimSpace(strings.Join(rest, "\n")), examples
+	}
+	return doc, nil
+}
+
 func (rmd *RawMetadata) SetComment(comment *ast.CommentGroup) {
 	rmd.Comment = strings.TrimSpace(comment.Text())
 }
@@ -66,9 +130,16 @@ func (rmd *RawMetadata) Child(name string) *RawMetadata {
 	return child
 }
 
+// Encode encodes rmd deterministically (JSON sorts map keys), preceded by a
+// header with the format version.
 func (rmd *RawMetadata) Encode() []byte {
 	var b bytes.Buffer
-	assert.Nil(gob.NewEncoder(&b).Encode(rmd))
+	fmt.Fprintf(&b, "%v%v\n", metadataHeader, MetadataVersion)
+	e := json.NewEncoder(&b)
+	// Keep usages like "<out>" readable (in code review, say).
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "\t")
+	assert.Nil(e.Encode(rmd))
 	return b.Bytes()
 }
 
@@ -76,22 +147,72 @@ type Metadata struct {
 	root     *Metadata
 	raw      *RawMetadata
 	children map[string]*Metadata
+	// missing funcs / types looked up (only tracked in the root), see Missing.
+	missing []string
 }
 
-func DecodeAsMetadata(b []byte) *Metadata {
-	md := &Metadata{raw: DecodeAsRawMetadata(b)}
+func DecodeAsMetadata(b []byte) (*Metadata, error) {
+	rmd, err := DecodeAsRawMetadata(b)
+	if err != nil {
+		return nil, err
+	}
+	md := &Metadata{raw: rmd}
 	md.root = md
-	return md
+	return md, nil
 }
 
 func (md *Metadata) Lookup(pkgPath, name string) *Metadata {
 	if md == nil {
 		return nil
 	}
+	pkg, ok := md.root.raw.Children[pkgPath]
+	if (!ok || pkg.Children[name] == nil) && inScope(pkgPath) && !unnamed(pkgPath, name) {
+		md.root.missing = append(md.root.missing, pkgPath+"."+name)
+	}
 	return md.root.Child(pkgPath).Child(name)
 }
 
+// unnamed reports whether the func (as named by runtime.FuncForPC) is a method
+// value ("pkg.(*T).M-fm") or a closure ("pkg.f.func1"), neither of which can be
+// in the metadata (the pkgPath is then qualified by the receiver / enclosing
+// func, while dots in the last element of an actual package path are escaped).
+func unnamed(pkgPath, name string) bool {
+	last := pkgPath[strings.LastIndex(pkgPath, "/")+1:]
+	return strings.HasSuffix(name, "-fm") || strings.Contains(last, ".")
+}
+
+var mainModule = sync.OnceValue(func() string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		return bi.Main.Path
+	}
+	return ""
+})
+
+// inScope reports whether the package is expected to be in the metadata at all,
+// i.e., whether it's from the main module (cligen doesn't parse dependencies).
+func inScope(pkgPath string) bool {
+	mod := mainModule()
+	if pkgPath == "main" || mod == "" {
+		return true
+	}
+	return pkgPath == mod || strings.HasPrefix(pkgPath, mod+"/")
+}
+
+// Missing returns the funcs / types (as pkgPath.name) that were looked up but
+// are absent from the metadata, which usually means that it's stale.
+func (md *Metadata) Missing() []string {
+	if md == nil {
+		return nil
+	}
+	return md.root.missing
+}
+
 func (md *Metadata) LookupType(t reflect.Type) *Metadata {
+	if t.Name() == "" {
+		// Unnamed types (struct{ Host string }, say) have no metadata of their
+		// own (their fields' docs are not parsed either).
+		return nil
+	}
 	return md.Lookup(t.PkgPath(), t.Name())
 }
 
@@ -111,6 +232,75 @@ func (md *Metadata) Aliases() []string {
 	return aliases
 }
 
+// Group returns the title of the group (of commands in help) the command is in.
+func (md *Metadata) Group() string {
+	if md == nil {
+		return ""
+	}
+	return md.raw.Directives["group"]
+}
+
+func (md *Metadata) Hidden() bool {
+	if md == nil {
+		return false
+	}
+	_, ok := md.raw.Directives["hidden"]
+	return ok
+}
+
+// DefaultDeprecation is the deprecation message for commands (and flags) that
+// are deprecated without one (as Cobra and pflag require one).
+const DefaultDeprecation = "it may be removed in a future release"
+
+func (md *Metadata) Deprecated() string {
+	if md == nil {
+		return ""
+	}
+	v, ok := md.raw.Directives["deprecated"]
+	if ok && v == "" {
+		return DefaultDeprecation
+	}
+	return v
+}
+
+// Files returns the (pipe separated) file patterns to complete the args with
+// and whether there's a file directive at all.
+func (md *Metadata) Files() (string, bool) {
+	if md == nil {
+		return "", false
+	}
+	v, ok := md.raw.Directives["file"]
+	return v, ok
+}
+
+func (md *Metadata) Dir() bool {
+	if md == nil {
+		return false
+	}
+	_, ok := md.raw.Directives["dir"]
+	return ok
+}
+
+func (md *Metadata) Params() []string {
+	if md == nil {
+		return nil
+	}
+	return md.raw.Params
+}
+
+// Example returns the examples formatted as Cobra expects them (indented, one
+// per line).
+func (md *Metadata) Example() string {
+	if md == nil {
+		return ""
+	}
+	examples := make([]string, len(md.raw.Examples))
+	for i, example := range md.raw.Examples {
+		examples[i] = "  " + example
+	}
+	return strings.Join(examples, "\n")
+}
+
 func (md *Metadata) Long() string {
 	if md == nil {
 		return ""
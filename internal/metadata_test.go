@@ -0,0 +1,212 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/avamsi/climate/internal"
+)
+
+func TestSetDocExamples(t *testing.T) {
+	const src = `package p
+
+// Move changes from a revision into its parent.
+//
+// Examples:
+//
+//	jj squash
+//	jj squash -r @-
+//
+// The child revision is abandoned if it's empty.
+//
+//cli:aliases am
+//cli:example jj squash -i
+//cli:example jj am
+func Squash() {}
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rmd internal.RawMetadata
+	rmd.SetDoc(f.Comments[0])
+	want := internal.RawMetadata{
+		Doc: "Move changes from a revision into its parent.\n\n" +
+			"The child revision is abandoned if it's empty.",
+		Directives: map[string]string{"aliases": "am"},
+		Examples:   []string{"jj squash", "jj squash -r @-", "jj squash -i", "jj am"},
+	}
+	if diff := cmp.Diff(want, rmd); diff != "" {
+		t.Errorf("SetDoc() diff (-want +got):\n%v", diff)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	rmd := &internal.RawMetadata{
+		Children: map[string]*internal.RawMetadata{
+			"main": {
+				Doc:        "Greet someone.",
+				Directives: map[string]string{"usage": "greet <name>", "aliases": "hi"},
+				Params:     []string{"opts", "name"},
+			},
+		},
+	}
+	encoded := rmd.Encode()
+	if !bytes.HasPrefix(encoded, []byte("climate-metadata v1\n")) {
+		t.Errorf("Encode() = %q, want a version header", encoded)
+	}
+	for range 10 {
+		if got := rmd.Encode(); !bytes.Equal(got, encoded) {
+			t.Fatalf("Encode() = %q, want %q (deterministic)", got, encoded)
+		}
+	}
+	got, err := internal.DecodeAsRawMetadata(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(rmd, got); diff != "" {
+		t.Errorf("DecodeAsRawMetadata(Encode()) diff (-want +got):\n%v", diff)
+	}
+	// Legacy (gob encoded) metadata is still supported.
+	var legacy bytes.Buffer
+	if err := gob.NewEncoder(&legacy).Encode(rmd); err != nil {
+		t.Fatal(err)
+	}
+	got, err = internal.DecodeAsRawMetadata(legacy.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(rmd, got); diff != "" {
+		t.Errorf("DecodeAsRawMetadata(gob) diff (-want +got):\n%v", diff)
+	}
+	// Other versions are not.
+	_, err = internal.DecodeAsRawMetadata([]byte("climate-metadata v2\n{}"))
+	if want := `unsupported metadata version "2"`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("DecodeAsRawMetadata(v2) = %v, want %v...", err, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := &internal.RawMetadata{
+		Children: map[string]*internal.RawMetadata{
+			"main": {
+				Children: map[string]*internal.RawMetadata{
+					"jj": {
+						Doc: "Jujutsu.",
+						Children: map[string]*internal.RawMetadata{
+							"Init":  {Doc: "Create a new repo.", Directives: map[string]string{"dir": ""}},
+							"Debug": {Doc: "Debug."},
+						},
+					},
+				},
+			},
+		},
+	}
+	new := &internal.RawMetadata{
+		Children: map[string]*internal.RawMetadata{
+			"main": {
+				Children: map[string]*internal.RawMetadata{
+					"jj": {
+						Doc: "Jujutsu.",
+						Children: map[string]*internal.RawMetadata{
+							"Init":   {Doc: "Create a new repo\nin the given directory."},
+							"Squash": {Doc: "Squash."},
+						},
+					},
+				},
+			},
+		},
+	}
+	want := `- main.jj.Debug
+~ main.jj.Init
+    doc:
+      - Create a new repo.
+      + Create a new repo
+      + in the given directory.
+    directive dir:
+      - //cli:dir
++ main.jj.Squash
+`
+	if diff := cmp.Diff(want, internal.Diff(old, new)); diff != "" {
+		t.Errorf("Diff() diff (-want +got):\n%v", diff)
+	}
+	if got := internal.Diff(old, old); got != "" {
+		t.Errorf("Diff(old, old) = %q, want empty", got)
+	}
+}
+
+func TestDump(t *testing.T) {
+	rmd := &internal.RawMetadata{
+		Children: map[string]*internal.RawMetadata{
+			"main": {
+				Children: map[string]*internal.RawMetadata{
+					"jj": {
+						Doc: "Jujutsu.",
+						Children: map[string]*internal.RawMetadata{
+							"Repository": {Comment: "path to the repo"},
+							"Squash": {
+								Doc:        "Move changes into the parent.\n\nSee also jj split.",
+								Directives: map[string]string{"hidden": "", "aliases": "am"},
+								Params:     []string{"opts", "paths"},
+								Examples:   []string{"jj squash"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	want := `main
+  jj
+    // Jujutsu.
+    Repository
+      // path to the repo
+    Squash(opts, paths)
+      // Move changes into the parent.
+      //
+      // See also jj split.
+      //cli:aliases am
+      //cli:hidden
+      //cli:example jj squash
+`
+	if diff := cmp.Diff(want, internal.Dump(rmd)); diff != "" {
+		t.Errorf("Dump() diff (-want +got):\n%v", diff)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	rmd := &internal.RawMetadata{
+		Children: map[string]*internal.RawMetadata{
+			"github.com/avamsi/climate/internal": {
+				Children: map[string]*internal.RawMetadata{
+					"greet": {Doc: "Greet someone."},
+				},
+			},
+		},
+	}
+	md, err := internal.DecodeAsMetadata(rmd.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lookup := range [][2]string{
+		{"github.com/avamsi/climate/internal", "greet"},
+		{"github.com/avamsi/climate/internal", "serve"},
+		// Method values and closures can never be in the metadata.
+		{"github.com/avamsi/climate/internal.(*server)", "Run-fm"},
+		{"github.com/avamsi/climate/internal.serve", "func1"},
+		// Neither can packages from outside the main module.
+		{"github.com/spf13/cobra", "Command"},
+	} {
+		md.Lookup(lookup[0], lookup[1])
+	}
+	want := []string{"github.com/avamsi/climate/internal.serve"}
+	if diff := cmp.Diff(want, md.Missing()); diff != "" {
+		t.Errorf("Missing() diff (-want +got):\n%v", diff)
+	}
+}
@@ -16,6 +16,36 @@ const (
 	ArbitraryLengthParam
 )
 
+var paramTypeNames = [...]string{
+	NoParam:              "none",
+	RequiredParam:        "required",
+	OptionalParam:        "optional",
+	FixedLengthParam:     "fixed",
+	ArbitraryLengthParam: "arbitrary",
+}
+
+func (pt ParamType) String() string {
+	if int(pt) < len(paramTypeNames) {
+		return paramTypeNames[pt]
+	}
+	return fmt.Sprintf("ParamType(%d)", int(pt))
+}
+
+// MarshalText marshals pt as its name (so that it's readable in JSON, say).
+func (pt ParamType) MarshalText() ([]byte, error) {
+	return []byte(pt.String()), nil
+}
+
+func (pt *ParamType) UnmarshalText(b []byte) error {
+	for i, name := range paramTypeNames {
+		if name == string(b) {
+			*pt = ParamType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown ParamType: %q", b)
+}
+
 func ParamTypes(f reflect.Type) []ParamType {
 	var types []ParamType
 	for i := 0; i < f.NumIn(); i++ {
@@ -3,9 +3,30 @@ package internal
 import "context"
 
 type Plan interface {
-	Execute(context.Context, *Metadata) error
+	Execute(context.Context, *Metadata, *RunOptions) error
 }
 
 type RunOptions struct {
-	Metadata *[]byte
+	Metadata            *[]byte
+	CompletionInstaller bool
+	// Width to wrap help at, zero means to detect it (see climate.helpWidth).
+	Width int
+	Theme *Theme
+	// Templates for help and usage, empty means to use the default ones (see
+	// climate.DefaultHelpTemplate and climate.DefaultUsageTemplate).
+	HelpTemplate, UsageTemplate string
+	// MissingMetadata is called with the funcs / types (as pkgPath.name) that
+	// are absent from the metadata, if any.
+	MissingMetadata func(missing []string)
+}
+
+// Theme is a set of ANSI styles (SGR parameters, "1;36" for bold cyan, say) for
+// help and error output, where empty styles are not applied at all.
+type Theme struct {
+	Heading    string // section headings ("Flags:", say)
+	Flag       string // flag names
+	Type       string // flag types
+	Default    string // flag defaults
+	Error      string // the "Error:" prefix
+	Suggestion string // suggested commands ("Did you mean this?")
 }
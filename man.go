@@ -0,0 +1,106 @@
+package climate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/avamsi/climate/internal"
+)
+
+// roffEscape escapes the given text for use in roff (as is, in no-fill mode).
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		// Lines starting with these are otherwise interpreted as requests.
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func manName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+}
+
+func manSection(b *strings.Builder, title, text string) {
+	fmt.Fprintf(b, ".SH %v\n.nf\n%v\n.fi\n", title, roffEscape(strings.TrimRight(text, "\n ")))
+}
+
+// manPage returns the section 1 man page (in roff) for the given command.
+func manPage(cmd *cobra.Command) string {
+	var (
+		b    strings.Builder
+		name = manName(cmd)
+		root = cmd.Root()
+	)
+	fmt.Fprintf(&b, ".TH \"%v\" \"1\" \"\" \"%v\" \"User Commands\"\n",
+		strings.ToUpper(name), strings.TrimSpace(root.Name()+" "+root.Version))
+	fmt.Fprintf(&b, ".SH NAME\n%v \\- %v\n", roffEscape(name), roffEscape(cmd.Short))
+	synopsis := cmd.UseLine()
+	if cmd.HasAvailableSubCommands() {
+		synopsis += "\n" + cmd.CommandPath() + " [command]"
+	}
+	manSection(&b, "SYNOPSIS", synopsis)
+	if long := cmd.Long; long != "" {
+		manSection(&b, "DESCRIPTION", long)
+	}
+	if len(cmd.Aliases) > 0 {
+		manSection(&b, "ALIASES", cmd.NameAndAliases())
+	}
+	if cmd.HasExample() {
+		manSection(&b, "EXAMPLES", cmd.Example)
+	}
+	if cmd.HasAvailableLocalFlags() {
+		manSection(&b, "OPTIONS", flagUsages(cmd.LocalFlags(), 0, Theme{}))
+	}
+	if cmd.HasAvailableInheritedFlags() {
+		manSection(&b, "GLOBAL OPTIONS", flagUsages(cmd.InheritedFlags(), 0, Theme{}))
+	}
+	var seeAlso []*cobra.Command
+	if cmd.HasParent() {
+		seeAlso = append(seeAlso, cmd.Parent())
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+			seeAlso = append(seeAlso, sub)
+		}
+	}
+	if len(seeAlso) > 0 {
+		refs := make([]string, len(seeAlso))
+		for i, c := range seeAlso {
+			refs[i] = fmt.Sprintf("\\fB%v\\fP(1)", roffEscape(manName(c)))
+		}
+		fmt.Fprintf(&b, ".SH SEE ALSO\n%v\n", strings.Join(refs, ", "))
+	}
+	return b.String()
+}
+
+func manFile(cmd *cobra.Command) string {
+	return manName(cmd) + ".1"
+}
+
+func manCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:    "man <dir>",
+		Short:  "Generate man pages",
+		Long:   "Generate man pages (in roff) for all the commands in the given directory.",
+		Args:   cobra.ExactArgs(1),
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeDocs(cmd.Root(), args[0], manFile, manPage)
+		},
+	}
+}
+
+// GenerateManPages writes section 1 man pages (in roff) for all the commands
+// in the given plan to dir, one per command (named after its command path).
+// Man pages are also available through a hidden "man" subcommand (for plans
+// with subcommands).
+func GenerateManPages(p internal.Plan, dir string, mods ...func(*internal.RunOptions)) error {
+	return writeDocs(&build(p, mods).delegate, dir, manFile, manPage)
+}
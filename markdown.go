@@ -0,0 +1,122 @@
+package climate
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/avamsi/climate/internal"
+)
+
+func markdownFile(cmd *cobra.Command) string {
+	return manName(cmd) + ".md"
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func markdownFlagTable(b *strings.Builder, title string, fset *pflag.FlagSet) {
+	fmt.Fprintf(b, "\n## %v\n\n", title)
+	b.WriteString("| Flag | Type | Default | Description |\n")
+	b.WriteString("| ---- | ---- | ------- | ----------- |\n")
+	fset.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		name := fmt.Sprintf("`--%v`", f.Name)
+		if f.Shorthand != "" {
+			name = fmt.Sprintf("`-%v`, %v", f.Shorthand, name)
+		}
+		if aliases, ok := f.Annotations[flagAliases]; ok {
+			name += fmt.Sprintf(" (aliases: `--%v`)", strings.Join(aliases, "`, `--"))
+		}
+		var (
+			qtype, usage = pflag.UnquoteUsage(f)
+			value        string
+		)
+		if _, ok := f.Annotations[nonZeroDefault]; ok {
+			value = fmt.Sprintf("`%v`", f.DefValue)
+		}
+		if group, ok := f.Annotations[flagGroup]; ok {
+			usage = fmt.Sprintf("%v (%v)", usage, group[0])
+		}
+		fmt.Fprintf(b, "| %v | %v | %v | %v |\n",
+			name, markdownCell(qtype), value, markdownCell(usage))
+	})
+	if c := constraintUsages(fset, Theme{}); c != "" {
+		fmt.Fprintf(b, "\n```\n%v```\n", strings.TrimPrefix(c, "\n"))
+	}
+}
+
+func markdownLink(cmd *cobra.Command) string {
+	return fmt.Sprintf("[`%v`](%v)", cmd.CommandPath(), markdownFile(cmd))
+}
+
+// markdownPage returns the Markdown reference docs for the given command.
+func markdownPage(cmd *cobra.Command) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %v\n", cmd.CommandPath())
+	// The long description usually starts with the short one (see Metadata's
+	// Short), so only fall back to the short description if there's no long.
+	if desc := cmp.Or(strings.TrimSpace(cmd.Long), cmd.Short); desc != "" {
+		fmt.Fprintf(&b, "\n%v\n", desc)
+	}
+	fmt.Fprintf(&b, "\n## Usage\n\n```\n%v\n", cmd.UseLine())
+	if cmd.HasAvailableSubCommands() {
+		fmt.Fprintf(&b, "%v [command]\n", cmd.CommandPath())
+	}
+	b.WriteString("```\n")
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(&b, "\n**Aliases:** `%v`\n", strings.Join(cmd.Aliases, "`, `"))
+	}
+	if cmd.HasExample() {
+		fmt.Fprintf(&b, "\n## Examples\n\n```\n%v\n```\n", strings.TrimRight(cmd.Example, "\n "))
+	}
+	if cmd.HasAvailableLocalFlags() {
+		markdownFlagTable(&b, "Flags", cmd.LocalFlags())
+	}
+	if cmd.HasAvailableInheritedFlags() {
+		markdownFlagTable(&b, "Global Flags", cmd.InheritedFlags())
+	}
+	if cmd.HasAvailableSubCommands() {
+		b.WriteString("\n## Commands\n\n| Command | Description |\n| ------- | ----------- |\n")
+		for _, sub := range cmd.Commands() {
+			if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+				fmt.Fprintf(&b, "| %v | %v |\n", markdownLink(sub), markdownCell(sub.Short))
+			}
+		}
+	}
+	if cmd.HasParent() {
+		fmt.Fprintf(&b, "\n## See Also\n\n- %v: %v\n", markdownLink(cmd.Parent()), cmd.Parent().Short)
+	}
+	return b.String()
+}
+
+// addMarkdownFlag adds a hidden --generate-markdown flag to root, which writes
+// Markdown reference docs (see GenerateMarkdown) to the given directory
+// (instead of running root). This is what cligen docs uses.
+func addMarkdownFlag(root *cobra.Command) {
+	const name = "generate-markdown"
+	var dir string
+	root.Flags().StringVar(&dir, name, "", "write Markdown reference docs to the given `dir`")
+	_ = root.Flags().MarkHidden(name)
+	_ = root.MarkFlagDirname(name)
+	intercept(root, func() bool { return dir != "" }, func(cmd *cobra.Command) error {
+		return writeDocs(cmd, dir, markdownFile, markdownPage)
+	})
+}
+
+// GenerateMarkdown writes Markdown reference docs for all the commands in the
+// given plan to dir, one file per command (named after its command path and
+// cross-linked to its parent and children). The output is deterministic, so it
+// can be committed and diff-checked (in CI, for example).
+// The docs are also available through a hidden --generate-markdown flag (on the
+// root command), which is how cligen docs generates them for main packages.
+func GenerateMarkdown(p internal.Plan, dir string, mods ...func(*internal.RunOptions)) error {
+	return writeDocs(&build(p, mods).delegate, dir, markdownFile, markdownPage)
+}
@@ -2,7 +2,10 @@ package climate
 
 import (
 	"reflect"
+	"regexp"
+	"slices"
 	"strings"
+	"unicode"
 	"unsafe"
 
 	"github.com/avamsi/ergo"
@@ -20,13 +23,36 @@ type tags struct {
 	m map[string]string
 }
 
+// tagKeys are the keys supported in the "cli" tags (along with the names of the
+// validation rules and the constraint kinds, see isTagKey).
+var tagKeys = []string{
+	"short", "required", "hidden", "deprecated", "alias", "name", "file", "dir",
+	"prefix", "group",
+}
+
+func isTagKey(k string) bool {
+	if slices.Contains(tagKeys, k) || slices.Contains(ruleNames, k) {
+		return true
+	}
+	return slices.ContainsFunc(constraintKinds, func(kind constraintKind) bool {
+		return kind.tag == k
+	})
+}
+
 func newTags(st reflect.StructTag) tags {
 	m := make(map[string]string)
 	if v, ok := st.Lookup("default"); ok {
 		m["default"] = v
 	}
-	for _, kv := range strings.Split(st.Get("cli"), ",") {
+	cli := st.Get("cli")
+	if cli == "" {
+		return tags{m}
+	}
+	for _, kv := range strings.Split(cli, ",") {
 		k, v, _ := strings.Cut(kv, "=")
+		// Values can't have commas, so "pattern=^a{1,3}$" would otherwise be
+		// (silently) cut short, with "3}$" as an (ignored) key.
+		assert.Truef(isTagKey(k), "unknown cli tag key %q (in %q)", k, cli)
 		m[k] = v
 	}
 	return tags{m}
@@ -47,39 +73,233 @@ func (ts tags) required() bool {
 	return ok
 }
 
+func (ts tags) hidden() bool {
+	_, ok := ts.m["hidden"]
+	return ok
+}
+
+func (ts tags) deprecated() (string, bool) {
+	v, ok := ts.m["deprecated"]
+	if ok && v == "" {
+		v = internal.DefaultDeprecation
+	}
+	return v, ok
+}
+
+// aliases returns the (pipe separated) alternative names for the flag.
+func (ts tags) aliases() []string {
+	v, ok := ts.m["alias"]
+	if !ok {
+		return nil
+	}
+	var aliases []string
+	for _, alias := range strings.Split(v, "|") {
+		alias = internal.NormalizeToKebabCase(alias)
+		assert.Truef(alias != "", "empty alias: %q", v)
+		aliases = append(aliases, alias)
+	}
+	return aliases
+}
+
+func (ts tags) explicitName() (string, bool) {
+	v, ok := ts.m["name"]
+	return v, ok
+}
+
+// files returns the file extensions to complete the flag's values with (nil, if
+// any file goes) and whether there's a "file" tag at all.
+func (ts tags) files() ([]string, bool) {
+	v, ok := ts.m["file"]
+	return fileExtensions(v), ok
+}
+
+func (ts tags) dir() bool {
+	_, ok := ts.m["dir"]
+	return ok
+}
+
+func (ts tags) prefix() (string, bool) {
+	v, ok := ts.m["prefix"]
+	return v, ok
+}
+
+// helpGroup returns the heading of the group of flags (in help) the flag (or
+// all the flags of the nested options struct) is in, where an empty heading
+// means no group at all.
+func (ts tags) helpGroup() (string, bool) {
+	v, ok := ts.m["group"]
+	return v, ok
+}
+
+// constraintGroups returns the (pipe separated) names of the groups of the
+// given kind of constraint this flag is part of.
+func (ts tags) constraintGroups(kind constraintKind) []string {
+	v, ok := ts.m[kind.tag]
+	if !ok {
+		return nil
+	}
+	var groups []string
+	for _, group := range strings.Split(v, "|") {
+		group = strings.TrimSpace(group)
+		assert.Truef(group != "", "empty %v group: %q", kind.tag, v)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// rules returns the validation rules declared in the tags (as "name[=arg]").
+func (ts tags) rules() []string {
+	var rules []string
+	for _, name := range ruleNames {
+		arg, ok := ts.m[name]
+		switch {
+		case !ok:
+			continue
+		case arg == "":
+			rules = append(rules, name)
+		default:
+			rules = append(rules, name+"="+arg)
+		}
+	}
+	return rules
+}
+
 type option struct {
-	fset *pflag.FlagSet
-	t    reflect.Type
-	p    unsafe.Pointer
-	name string
+	fset   *pflag.FlagSet
+	t      reflect.Type
+	p      unsafe.Pointer
+	prefix string
+	name   string
 	tags
 	usage string
+	group string
+}
+
+var explicitNameRegexp = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9._-]*$")
+
+// flagName returns the name of the flag, which is the (prefixed) field name in
+// kebab-case, unless a "name" tag is present, in which case it's used as is.
+func (opt *option) flagName() string {
+	v, ok := opt.explicitName()
+	if !ok {
+		name := opt.prefix + opt.name
+		assert.Truef(utf8string.NewString(name).IsASCII(), "not ASCII: %v", name)
+		// Declare flags with their normalized names upfront (instead of relying
+		// on the normalization in command.run) so that we can refer to them by
+		// those names when marking flag constraints etc.
+		return internal.NormalizeToKebabCase(name)
+	}
+	assert.Truef(utf8string.NewString(v).IsASCII(), "not ASCII: %v", v)
+	assert.Truef(explicitNameRegexp.MatchString(v), "malformed name: %q", v)
+	if opt.prefix != "" {
+		v = internal.NormalizeToKebabCase(opt.prefix) + "-" + v
+	}
+	return v
+}
+
+// aliasNames returns the alternative names for the flag, prefixed (like
+// explicit names in flagName) for flags of nested options structs.
+func (opt *option) aliasNames() []string {
+	aliases := opt.aliases()
+	if opt.prefix != "" {
+		for i, alias := range aliases {
+			aliases[i] = internal.NormalizeToKebabCase(opt.prefix) + "-" + alias
+		}
+	}
+	return aliases
+}
+
+// assertAvailable asserts that name is neither the name nor an alias of any of
+// the flags already declared in fset.
+func assertAvailable(fset *pflag.FlagSet, name string) {
+	exact, primary := lookupFlag(fset, name)
+	assert.Truef(!exact, "flag redefined: %v", name)
+	assert.Truef(primary == "", "flag redefined: %v (alias of %v)", name, primary)
 }
 
-const nonZeroDefault = "climate_annotation_non_zero_default"
+const (
+	nonZeroDefault   = "climate_annotation_non_zero_default"
+	flagGroup        = "climate_annotation_flag_group"
+	flagAliases      = "climate_annotation_flag_aliases"
+	flagExplicitName = "climate_annotation_flag_explicit_name"
+)
 
 func declareOption[T any](flagVarP flagTypeVarP[T], opt *option, typer typeParser[T]) {
 	var (
 		p     = (*T)(opt.p)
+		name  = opt.flagName()
 		value T
 	)
+	assertAvailable(opt.fset, name)
 	if v, ok := opt.defaultValue(); ok {
 		value = typer(v)
 		defer func() {
-			assert.Nil(opt.fset.SetAnnotation(opt.name, nonZeroDefault, nil))
+			assert.Nil(opt.fset.SetAnnotation(name, nonZeroDefault, nil))
 		}()
 	}
-	assert.Truef(utf8string.NewString(opt.name).IsASCII(), "not ASCII: %v", opt.name)
 	var shorthand string
 	if v, ok := opt.shorthand(); ok {
 		if v == "" {
-			v = strings.ToLower(opt.name[:1])
+			v = opt.name
+			if n, ok := opt.explicitName(); ok {
+				v = n
+			}
+			v = strings.ToLower(v[:1])
 		}
 		shorthand = v
 	}
-	flagVarP(p, opt.name, shorthand, value, opt.usage)
+	flagVarP(p, name, shorthand, value, opt.usage)
+	if _, ok := opt.explicitName(); ok {
+		assert.Nil(opt.fset.SetAnnotation(name, flagExplicitName, nil))
+	}
 	if opt.required() {
-		assert.Nil(cobra.MarkFlagRequired(opt.fset, opt.name))
+		assert.Nil(cobra.MarkFlagRequired(opt.fset, name))
+	}
+	if aliases := opt.aliasNames(); len(aliases) > 0 {
+		for _, alias := range aliases {
+			assertAvailable(opt.fset, alias)
+		}
+		assert.Nil(opt.fset.SetAnnotation(name, flagAliases, aliases))
+	}
+	if exts, ok := opt.files(); ok {
+		assert.Nil(cobra.MarkFlagFilename(opt.fset, name, exts...))
+	}
+	if opt.dir() {
+		// Note: this only completes directories, see the "isdir" rule for
+		// validating that the value actually is one.
+		assert.Nil(cobra.MarkFlagDirname(opt.fset, name))
+	}
+	if opt.hidden() {
+		assert.Nil(opt.fset.MarkHidden(name))
+	}
+	if v, ok := opt.deprecated(); ok {
+		// Note: deprecated flags are hidden as well (by pflag).
+		assert.Nil(opt.fset.MarkDeprecated(name, v))
+	}
+	group := opt.group
+	if v, ok := opt.helpGroup(); ok {
+		group = v
+	}
+	if group != "" {
+		assert.Nil(opt.fset.SetAnnotation(name, flagGroup, []string{group}))
+	}
+	if rules := opt.rules(); len(rules) > 0 {
+		for _, r := range rules {
+			rn, arg, _ := strings.Cut(r, "=")
+			assert.Truef(ruleApplies(rn, opt.t), "%v not applicable to: %v", r, opt.t)
+			newRule(rn, arg) // panics if arg is malformed
+		}
+		assert.Nil(opt.fset.SetAnnotation(name, flagRules, rules))
+	}
+	for _, kind := range constraintKinds {
+		if groups := opt.constraintGroups(kind); len(groups) > 0 {
+			// Scope the groups to the nested options struct (if any), so that
+			// reusing it (with different prefixes) doesn't merge the groups.
+			for i, group := range groups {
+				groups[i] = opt.prefix + group
+			}
+			assert.Nil(opt.fset.SetAnnotation(name, kind.annotation, groups))
+		}
 	}
 }
 
@@ -153,11 +373,102 @@ func (opt *option) declare() bool {
 	return true
 }
 
+// lookupFlag reports whether there's a flag in fset with the given name and
+// returns the name of the flag that has it as an alias (if any).
+func lookupFlag(fset *pflag.FlagSet, name string) (exact bool, primary string) {
+	// Note: we can't use fset.Lookup here, as it normalizes the name itself.
+	fset.VisitAll(func(f *pflag.Flag) {
+		if f.Name == name {
+			exact = true
+		}
+		if slices.Contains(f.Annotations[flagAliases], name) {
+			primary = f.Name
+		}
+	})
+	return exact, primary
+}
+
+// normalizeFlagName normalizes the given name to kebab-case (unless it's one of
+// the explicit names) and resolves it to the name of the flag in fset that has
+// it as an alias (if any).
+func normalizeFlagName(fset *pflag.FlagSet, name string, explicit map[string]bool) string {
+	if explicit[name] {
+		return name
+	}
+	name = internal.NormalizeToKebabCase(name)
+	if exact, primary := lookupFlag(fset, name); !exact && primary != "" {
+		return primary
+	}
+	return name
+}
+
 type options struct {
 	reflection
 	parent *reflection
+	// cmd is the command the flags are declared for (in fset, which is one of
+	// cmd's flag sets) and is used to register completions.
+	cmd    *cobra.Command
 	fset   *pflag.FlagSet
 	md     *internal.Metadata
+	prefix string
+	group  string
+}
+
+// groupHeading returns the help heading for the flags of a nested options
+// struct, derived from the field's doc (or name, if there's no doc).
+func groupHeading(name, usage string) string {
+	if usage == "" {
+		return name + " Flags"
+	}
+	rs := []rune(usage)
+	rs[0] = unicode.ToUpper(rs[0])
+	return strings.TrimSuffix(string(rs), ".")
+}
+
+// declareNested declares the fields of a nested (non-pointer) options struct
+// as flags, prefixed with the field name (or the "prefix" tag, if present) and
+// grouped under a heading derived from the field (or the "group" tag, if
+// present). Embedded options structs are not prefixed by default (similar to
+// how Go promotes the fields of embedded structs).
+func (opts *options) declareNested(f reflect.StructField, v reflect.Value, usage string) {
+	var (
+		ts      = newTags(f.Tag)
+		prefix  = f.Name
+		heading = groupHeading(f.Name, usage)
+	)
+	if f.Anonymous {
+		prefix = ""
+	}
+	if p, ok := ts.prefix(); ok {
+		prefix = p
+	}
+	if h, ok := ts.helpGroup(); ok {
+		heading = h
+	}
+	if prefix != "" {
+		// Normalization takes care of any duplicate dashes.
+		prefix += "-"
+	}
+	nested := &options{
+		reflection: reflection{ov: &v},
+		parent:     nil, // nested options can't reference the parent
+		cmd:        opts.cmd,
+		fset:       opts.fset,
+		md:         opts.md.LookupType(f.Type),
+		prefix:     opts.prefix + prefix,
+		group:      heading,
+	}
+	nested.declare()
+}
+
+// registerCompletion registers the Complete<field> method of the options struct
+// (if any, see companion) as the completion function for the named flag.
+func (opts *options) registerCompletion(field, name string) {
+	m := companion(opts.v().Addr(), field, false)
+	if !m.IsValid() {
+		return
+	}
+	assert.Nil(opts.cmd.RegisterFlagCompletionFunc(name, completionFunc(m, false)))
 }
 
 func (opts *options) declare() {
@@ -173,30 +484,36 @@ func (opts *options) declare() {
 		if usage == "" {
 			usage = md.Short()
 		}
-		var (
-			v   = opts.v().Field(i)
-			opt = option{
-				fset:  opts.fset,
-				t:     f.Type,
-				p:     v.Addr().UnsafePointer(),
-				name:  f.Name,
-				tags:  newTags(f.Tag),
-				usage: usage,
-			}
-		)
-		if !opt.declare() {
-			if opts.parent == nil {
-				ergo.Panicf("not bool | Integer | Float | string | []T: %v", f.Type)
-			}
-			if f.Type != opts.parent.ptr.t() {
-				ergo.Panicf(
-					"not bool | Integer | Float | string | []T | %v: %v",
-					opts.parent.t(), f.Type)
-			}
-			if parentSet {
-				ergo.Panicf("more than one parent: %v", f.Type)
-			}
-			v.Set(*opts.parent.ptr.v())
+		v := opts.v().Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			opts.declareNested(f, v, usage)
+			continue
+		}
+		opt := option{
+			fset:   opts.fset,
+			t:      f.Type,
+			p:      v.Addr().UnsafePointer(),
+			prefix: opts.prefix,
+			name:   f.Name,
+			tags:   newTags(f.Tag),
+			usage:  usage,
+			group:  opts.group,
+		}
+		if opt.declare() {
+			opts.registerCompletion(f.Name, opt.flagName())
+			continue
+		}
+		if opts.parent == nil {
+			ergo.Panicf("not bool | Integer | Float | string | []T: %v", f.Type)
+		}
+		if f.Type != opts.parent.ptr.t() {
+			ergo.Panicf(
+				"not bool | Integer | Float | string | []T | %v: %v",
+				opts.parent.t(), f.Type)
+		}
+		if parentSet {
+			ergo.Panicf("more than one parent: %v", f.Type)
 		}
+		v.Set(*opts.parent.ptr.v())
 	}
 }
@@ -8,11 +8,17 @@ import (
 	"github.com/avamsi/climate/internal"
 )
 
+// plan is implemented by all the plans returned by Func and Struct.
+type plan interface {
+	internal.Plan
+	build(md *internal.Metadata) *command
+}
+
 type funcPlan struct {
 	reflection
 }
 
-func (fp *funcPlan) Execute(ctx context.Context, md *internal.Metadata) error {
+func (fp *funcPlan) build(md *internal.Metadata) *command {
 	var (
 		name = runtime.FuncForPC(fp.v().Pointer()).Name()
 		dot  = strings.LastIndex(name, ".")
@@ -22,9 +28,15 @@ func (fp *funcPlan) Execute(ctx context.Context, md *internal.Metadata) error {
 		name,
 		fp.reflection,
 		md.Lookup(pkgPath, name),
+		nil, // no struct validators
 	}
-	cmd := fcb.build()
-	return cmd.run(ctx)
+	return fcb.build()
+}
+
+func (fp *funcPlan) Execute(ctx context.Context, md *internal.Metadata, opts *internal.RunOptions) error {
+	cmd := fp.build(md)
+	reportMissing(md, opts)
+	return cmd.run(ctx, opts)
 }
 
 type structPlan struct {
@@ -32,20 +44,34 @@ type structPlan struct {
 	subcommands []*structPlan
 }
 
-func (sp *structPlan) buildRecursive(parent *reflection, md *internal.Metadata) *command {
+func (sp *structPlan) buildRecursive(parent *reflection, validators []validator, md *internal.Metadata) *command {
 	scb := &structCommandBuilder{
 		sp.reflection,
 		parent,
 		md.LookupType(sp.t()),
+		validators,
 	}
 	cmd := scb.build()
 	for _, sub := range sp.subcommands {
-		cmd.addCommand(sub.buildRecursive(&sp.reflection, md))
+		cmd.addCommand(sub.buildRecursive(&sp.reflection, scb.validators, md))
 	}
 	return cmd
 }
 
-func (sp *structPlan) Execute(ctx context.Context, m *internal.Metadata) error {
-	root := sp.buildRecursive(nil, m) // no parent
-	return root.run(ctx)
+func (sp *structPlan) build(md *internal.Metadata) *command {
+	return sp.buildRecursive(nil, nil, md) // no parent (or its validators)
+}
+
+func (sp *structPlan) Execute(ctx context.Context, md *internal.Metadata, opts *internal.RunOptions) error {
+	cmd := sp.build(md)
+	reportMissing(md, opts)
+	return cmd.run(ctx, opts)
+}
+
+// reportMissing reports the funcs / types the (already built) plan looked up but
+// are missing from the metadata (if any) to the MissingMetadata hook (if any).
+func reportMissing(md *internal.Metadata, opts *internal.RunOptions) {
+	if missing := md.Missing(); len(missing) > 0 && opts.MissingMetadata != nil {
+		opts.MissingMetadata(missing)
+	}
 }
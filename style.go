@@ -0,0 +1,99 @@
+package climate
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/avamsi/ergo/assert"
+	"github.com/spf13/cobra"
+
+	"github.com/avamsi/climate/internal"
+)
+
+// Theme is a set of ANSI styles (SGR parameters, "1;36" for bold cyan, say) for
+// help and error output, where empty styles are not applied at all. See
+// WithTheme.
+type Theme = internal.Theme
+
+// DefaultTheme is a sensible default for WithTheme, which can also be copied
+// and modified (to only change the flag style, say).
+var DefaultTheme = Theme{
+	Heading:    "1",
+	Flag:       "36",
+	Type:       "33",
+	Default:    "2",
+	Error:      "1;31",
+	Suggestion: "32",
+}
+
+// style styles s with the given SGR parameters (if any).
+func style(sgr, s string) string {
+	if sgr == "" {
+		return s
+	}
+	return "\x1b[" + sgr + "m" + s + "\x1b[0m"
+}
+
+var (
+	sgrRegexp        = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	emptyStyleRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m\x1b\\[0m")
+)
+
+// visibleWidth returns the width of s (in runes), ignoring any styles.
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(sgrRegexp.ReplaceAllString(s, ""))
+}
+
+// colorEnabled reports whether output to f should be styled, honoring NO_COLOR
+// and FORCE_COLOR (in that order of precedence) before checking whether f is a
+// terminal.
+func colorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v := os.Getenv("FORCE_COLOR"); v != "" && v != "0" && v != "false" {
+		return true
+	}
+	return isTerminal(f)
+}
+
+// helpOptions are the options for rendering help (and errors) for a command
+// tree. Cobra only supports global template funcs, so they're stored (encoded)
+// in an annotation on the root command instead (see helpOptionsOf).
+type helpOptions struct {
+	Width int
+	// Themes for stdout (help) and stderr (errors), zero if not styled.
+	Out, Err Theme
+}
+
+const helpOptionsAnnotation = "climate_annotation_help_options"
+
+func newHelpOptions(opts *internal.RunOptions) *helpOptions {
+	ho := &helpOptions{Width: helpWidth(opts.Width)}
+	if opts.Theme != nil {
+		if colorEnabled(os.Stdout) {
+			ho.Out = *opts.Theme
+		}
+		if colorEnabled(os.Stderr) {
+			ho.Err = *opts.Theme
+		}
+	}
+	return ho
+}
+
+func setHelpOptions(root *cobra.Command, ho *helpOptions) {
+	if root.Annotations == nil {
+		root.Annotations = map[string]string{}
+	}
+	root.Annotations[helpOptionsAnnotation] = string(assert.Ok(json.Marshal(ho)))
+}
+
+func helpOptionsOf(cmd *cobra.Command) *helpOptions {
+	var ho helpOptions
+	if v, ok := cmd.Root().Annotations[helpOptionsAnnotation]; ok {
+		assert.Nil(json.Unmarshal([]byte(v), &ho))
+	}
+	return &ho
+}
@@ -0,0 +1,95 @@
+package climate
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/avamsi/climate/internal"
+)
+
+// DefaultUsageTemplate is the usage template used unless overridden (with
+// WithUsageTemplate). It's Cobra's default usage template, except that it uses
+// the template funcs below (see WithUsageTemplate).
+const DefaultUsageTemplate = `{{heading $ "Usage:"}}{{if runnable .}}
+  {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+  {{.CommandPath}} [command]{{end}}{{if gt (len .Aliases) 0}}
+
+{{heading $ "Aliases:"}}
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+{{heading $ "Examples:"}}
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}{{$cmds := .Commands}}{{if eq (len .Groups) 0}}
+
+{{heading $ "Available Commands:"}}{{range $cmds}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{else}}{{range $group := .Groups}}
+
+{{heading $ .Title}}{{range $cmds}}{{if (and (eq .GroupID $group.ID) (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if not .AllChildCommandsHaveGroup}}
+
+{{heading $ "Other Commands:"}}{{range $cmds}}{{if (and (eq .GroupID "") (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+{{heading $ "Flags:"}}
+{{.LocalFlags | flagUsages $ | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+{{heading $ "Global Flags:"}}
+{{.InheritedFlags | flagUsages $ | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+
+{{heading $ "Additional help topics:"}}{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+Use "{{.CommandPath}} [command] --help" for more information about a command.{{end}}
+`
+
+// DefaultHelpTemplate is the help template used unless overridden (with
+// WithHelpTemplate). Similar to DefaultUsageTemplate, it's Cobra's default help
+// template, except that it uses the template funcs below.
+const DefaultHelpTemplate = `{{with (or .Long .Short)}}{{. | wrapText $ | trimTrailingWhitespaces}}
+
+{{end}}{{if or .Runnable .HasSubCommands}}{{.UsageString}}{{end}}`
+
+// structCommand annotates struct commands, which are only "runnable" to validate
+// that there are no args (see structCommandBuilder.build).
+const structCommand = "climate_annotation_struct_command"
+
+func init() {
+	cobra.AddTemplateFuncs(map[string]any{
+		// Aligns the flag usages as a table (pflag's FlagUsages already does
+		// this to some extent but doesn't align types and default values) and
+		// wraps (and styles) them to fit in the terminal.
+		"flagUsages": func(cmd *cobra.Command, fset *pflag.FlagSet) string {
+			ho := helpOptionsOf(cmd)
+			return flagUsages(fset, ho.Width, ho.Out)
+		},
+		"wrapText": func(cmd *cobra.Command, s string) string {
+			return wrapText(helpOptionsOf(cmd).Width, s)
+		},
+		"heading": func(cmd *cobra.Command, s string) string {
+			return style(helpOptionsOf(cmd).Out.Heading, s)
+		},
+		"runnable": func(cmd *cobra.Command) bool {
+			_, ok := cmd.Annotations[structCommand]
+			return cmd.Runnable() && !ok
+		},
+		"describe": describe,
+	})
+}
+
+// setupTemplates sets up the help and usage templates (and the error prefix)
+// of root as per the given options.
+func setupTemplates(root *cobra.Command, opts *internal.RunOptions) {
+	ho := newHelpOptions(opts)
+	setHelpOptions(root, ho)
+	if prefix := root.ErrPrefix(); ho.Err.Error != "" {
+		root.SetErrPrefix(style(ho.Err.Error, prefix))
+	}
+	usage, help := DefaultUsageTemplate, DefaultHelpTemplate
+	if opts.UsageTemplate != "" {
+		usage = opts.UsageTemplate
+	}
+	if opts.HelpTemplate != "" {
+		help = opts.HelpTemplate
+	}
+	root.SetUsageTemplate(usage)
+	root.SetHelpTemplate(help)
+}
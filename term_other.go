@@ -0,0 +1,17 @@
+//go:build !(linux || darwin)
+
+package climate
+
+import "os"
+
+// terminalWidth returns zero (i.e., no wrapping) as detecting the width of the
+// terminal is not supported on this platform (COLUMNS still works, though).
+func terminalWidth(*os.File) int {
+	return 0
+}
+
+// isTerminal returns false as detecting terminals is not supported on this
+// platform (FORCE_COLOR still works, though).
+func isTerminal(*os.File) bool {
+	return false
+}
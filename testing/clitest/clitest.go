@@ -18,7 +18,14 @@ type Result struct {
 
 type TestCLI func(ctx context.Context, args []string) Result
 
+// width is the (fixed) width help is wrapped at, so that the results don't
+// depend on the terminal (or COLUMNS) the tests are run in.
+const width = 80
+
 func New(p internal.Plan, mods ...func(*internal.RunOptions)) TestCLI {
+	mods = append([]func(*internal.RunOptions){
+		func(opts *internal.RunOptions) { opts.Width = width },
+	}, mods...)
 	return func(ctx context.Context, args []string) Result {
 		var (
 			// TODO: do these pipes have enough capacity?
@@ -0,0 +1,190 @@
+package climate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/avamsi/ergo"
+	"github.com/avamsi/ergo/assert"
+	"github.com/spf13/pflag"
+)
+
+const flagRules = "climate_annotation_rules"
+
+// ruleNames are the names of the validation rules that can be declared on a
+// flag (using the "cli" tags), in the order they're checked in.
+var ruleNames = []string{"nonempty", "min", "max", "pattern", "exists", "isdir"}
+
+// rule checks a single (string formatted) flag value and describes the
+// violation, if any.
+type rule func(v string) error
+
+func typeIsNumeric(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// ruleApplies reports whether the named rule can be declared on flags of the
+// given type (rules on slices apply to each of their elements).
+func ruleApplies(name string, t reflect.Type) bool {
+	if name == "nonempty" {
+		return t.Kind() == reflect.String || t.Kind() == reflect.Slice
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch name {
+	case "min", "max":
+		return typeIsNumeric(t)
+	default:
+		return t.Kind() == reflect.String
+	}
+}
+
+// newRule returns the named rule with the given argument, panicking if the rule
+// is unknown or its argument is malformed.
+func newRule(name, arg string) rule {
+	switch name {
+	case "nonempty":
+		return nil // checked against all the values at once (see validateFlags)
+	case "min", "max":
+		bound := assert.Ok(strconv.ParseFloat(arg, 64))
+		return func(v string) error {
+			f := assert.Ok(strconv.ParseFloat(v, 64))
+			if name == "min" && f < bound {
+				return fmt.Errorf("must be at least %v", arg)
+			}
+			if name == "max" && f > bound {
+				return fmt.Errorf("must be at most %v", arg)
+			}
+			return nil
+		}
+	case "pattern":
+		re := regexp.MustCompile(arg)
+		return func(v string) error {
+			if !re.MatchString(v) {
+				return fmt.Errorf("must match %v", arg)
+			}
+			return nil
+		}
+	case "exists", "isdir":
+		return func(v string) error {
+			info, err := os.Stat(v)
+			if errors.Is(err, fs.ErrNotExist) {
+				return errors.New("no such file or directory")
+			}
+			if err != nil {
+				return err
+			}
+			if name == "isdir" && !info.IsDir() {
+				return errors.New("not a directory")
+			}
+			return nil
+		}
+	}
+	ergo.Panicf("unknown rule: %v", name)
+	return nil
+}
+
+func flagValues(f *pflag.Flag) []string {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.GetSlice()
+	}
+	return []string{f.Value.String()}
+}
+
+// validateFlags checks the values of the flags in the given flag set against
+// their declared rules and returns a usage error on the first violation.
+// Except for nonempty, rules are only checked against flags that are either
+// explicitly set or have a (non-zero) default, so that optional flags can be
+// left unset.
+func validateFlags(fset *pflag.FlagSet) error {
+	var err error
+	fset.VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		_, hasDefault := f.Annotations[nonZeroDefault]
+		values := flagValues(f)
+		for _, r := range f.Annotations[flagRules] {
+			name, arg, _ := strings.Cut(r, "=")
+			if name == "nonempty" {
+				if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+					err = ErrUsage(fmt.Errorf("--%v violates %v: must not be empty", f.Name, r))
+					return
+				}
+				continue
+			}
+			if !f.Changed && !hasDefault {
+				continue
+			}
+			check := newRule(name, arg)
+			for _, v := range values {
+				if verr := check(v); verr != nil {
+					err = ErrUsage(fmt.Errorf("--%v=%q violates %v: %w", f.Name, v, r, verr))
+					return
+				}
+			}
+		}
+	})
+	return err
+}
+
+// validator is the Validate method of an options struct (or a struct command),
+// normalized to always take a context.
+type validator func(context.Context) error
+
+// validatorOf returns the Validate method of the given struct pointer as a
+// validator, or nil if there's no such method with a matching signature, i.e.,
+// func([ctx context.Context]) error (otherwise it's just a regular method, a
+// subcommand on struct commands, say).
+func validatorOf(ptr reflect.Value) validator {
+	m := ptr.MethodByName("Validate")
+	if !m.IsValid() {
+		return nil
+	}
+	t := m.Type()
+	ok := (t.NumIn() == 0 || (t.NumIn() == 1 && typeIsContext(t.In(0)))) &&
+		t.NumOut() == 1 && typeIsError(t.Out(0))
+	if !ok {
+		return nil
+	}
+	return func(ctx context.Context) error {
+		var in []reflect.Value
+		if t.NumIn() == 1 {
+			in = append(in, reflect.ValueOf(ctx))
+		}
+		if out := m.Call(in); !out[0].IsNil() {
+			return out[0].Interface().(error)
+		}
+		return nil
+	}
+}
+
+// validate runs the given validators in order and returns the first error as
+// a usage error (unless it already is one).
+func validate(ctx context.Context, validators []validator) error {
+	for _, v := range validators {
+		err := v(ctx)
+		if err == nil { // if _no_ error
+			continue
+		}
+		if uerr := new(usageError); errors.As(err, &uerr) {
+			return err
+		}
+		return ErrUsage(err)
+	}
+	return nil
+}
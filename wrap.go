@@ -0,0 +1,89 @@
+package climate
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// minWrapWidth is the minimum width to wrap text at, below which wrapping does
+// more harm than good (think one word per line).
+const minWrapWidth = 20
+
+// helpWidth returns the width to wrap help at, which is (in that order of
+// precedence) the given width, the COLUMNS environment variable or the width of
+// the terminal (if stdout is one). Zero means no wrapping.
+func helpWidth(width int) int {
+	if width > 0 {
+		return width
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return terminalWidth(os.Stdout)
+}
+
+// wrapLine wraps line (at spaces) into lines of at most width runes (except
+// for words longer than width, which are not broken).
+func wrapLine(line string, width int) []string {
+	var (
+		lines []string
+		cur   strings.Builder
+		n     int
+	)
+	for _, word := range strings.Fields(line) {
+		w := utf8.RuneCountInString(word)
+		if n > 0 && n+1+w > width {
+			lines = append(lines, cur.String())
+			cur.Reset()
+			n = 0
+		}
+		if n > 0 {
+			cur.WriteByte(' ')
+			n++
+		}
+		cur.WriteString(word)
+		n += w
+	}
+	return append(lines, cur.String())
+}
+
+// wrapText wraps the lines of s that are wider than width, keeping their
+// indentation (on the continuation lines as well). Other lines are left as is,
+// as the text is usually already wrapped (docs, for example).
+func wrapText(width int, s string) string {
+	if width <= 0 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if utf8.RuneCountInString(line) <= width {
+			continue
+		}
+		var (
+			text   = strings.TrimLeft(line, " \t")
+			indent = line[:len(line)-len(text)]
+		)
+		// Tabs are expanded to (at most) 8 spaces, so assume the worst here.
+		available := width - len(indent) - 7*strings.Count(indent, "\t")
+		if available < minWrapWidth {
+			continue
+		}
+		lines[i] = indent + strings.Join(wrapLine(text, available), "\n"+indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapColumn wraps text to fit in width, assuming it starts at column start
+// (and indenting the continuation lines accordingly).
+func wrapColumn(text string, start, width int) string {
+	if width <= 0 || start+visibleWidth(text) <= width {
+		return text
+	}
+	available := width - start
+	if available < minWrapWidth {
+		return text
+	}
+	return strings.Join(wrapLine(text, available), "\n"+strings.Repeat(" ", start))
+}
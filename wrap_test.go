@@ -0,0 +1,64 @@
+package climate
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int
+		in    string
+		want  string
+	}{
+		{
+			name:  "no-width",
+			width: 0,
+			in:    "the quick brown fox jumps over the lazy dog",
+			want:  "the quick brown fox jumps over the lazy dog",
+		},
+		{
+			name:  "fits",
+			width: 43,
+			in:    "the quick brown fox jumps over the lazy dog",
+			want:  "the quick brown fox jumps over the lazy dog",
+		},
+		{
+			name:  "wraps",
+			width: 20,
+			in:    "the quick brown fox jumps over the lazy dog",
+			want:  "the quick brown fox\njumps over the lazy\ndog",
+		},
+		{
+			name:  "keeps-indentation",
+			width: 24,
+			in:    "short line\n    the quick brown fox jumps over the lazy dog",
+			want:  "short line\n    the quick brown fox\n    jumps over the lazy\n    dog",
+		},
+		{
+			name:  "too-narrow",
+			width: 10,
+			in:    "the quick brown fox jumps over the lazy dog",
+			want:  "the quick brown fox jumps over the lazy dog",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, wrapText(test.width, test.in)); diff != "" {
+				t.Errorf("wrapText(%v, %q) diff (-want +got):\n%v", test.width, test.in, diff)
+			}
+		})
+	}
+}
+
+func TestHelpWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "120")
+	if got := helpWidth(0); got != 120 {
+		t.Errorf("helpWidth(0) = %v, want 120 (from COLUMNS)", got)
+	}
+	if got := helpWidth(80); got != 80 {
+		t.Errorf("helpWidth(80) = %v, want 80", got)
+	}
+}